@@ -0,0 +1,213 @@
+// Package baseline collects a snapshot of facts about a host (e.g.
+// SUID files, listening sockets, autostart entries, executables of
+// running processes) that can be saved and compared against a later
+// scan.
+package baseline
+
+import (
+	"github.com/spyre-project/spyre/config"
+
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Snapshot maps categories to keys to values. Categories that have
+// been collected, but for which no items were found, are present
+// with an empty map.
+type Snapshot map[string]map[string]string
+
+var (
+	mu         sync.Mutex
+	current    = make(Snapshot)
+	incomplete = make(map[string]bool)
+)
+
+// addedOnly lists categories whose items come and go during normal
+// operation, such as running processes. Only new items are reported
+// for them.
+var addedOnly = map[string]bool{"process": true}
+
+// Enabled reports whether a baseline is to be saved or compared. Scan
+// modules should only collect items if this is the case.
+func Enabled() bool { return config.BaselineSave != "" || config.BaselineCompare != "" }
+
+// Collecting marks a category as collected in the current scan, even
+// if no items are recorded for it. Only collected categories are
+// compared.
+func Collecting(category string) {
+	if !Enabled() {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current.collecting(category)
+}
+
+func (s Snapshot) collecting(category string) map[string]string {
+	m, ok := s[category]
+	if !ok {
+		m = make(map[string]string)
+		s[category] = m
+	}
+	return m
+}
+
+// Record adds an item to the current snapshot. The value should
+// contain the properties whose change is relevant (e.g. hashes,
+// permissions).
+func Record(category, key, value string) {
+	if !Enabled() {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	current.collecting(category)[key] = value
+}
+
+// Incomplete marks a category as only partially collected in the
+// current scan, e.g. because a resumed scan skips files that have
+// been visited before. Such categories are neither compared nor
+// saved.
+func Incomplete(category string) {
+	mu.Lock()
+	defer mu.Unlock()
+	incomplete[category] = true
+}
+
+// Current returns the snapshot collected during the current scan,
+// without incomplete categories.
+func Current() Snapshot {
+	mu.Lock()
+	defer mu.Unlock()
+	s := make(Snapshot)
+	for c, m := range current {
+		if !incomplete[c] {
+			s[c] = m
+		}
+	}
+	return s
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`).Replace(s)
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+// Write writes the snapshot as gzip-compressed, sorted, tab-separated
+// lines. Collected categories are listed as lines starting with "#".
+func (s Snapshot) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	var categories []string
+	for c := range s {
+		categories = append(categories, c)
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		fmt.Fprintf(bw, "#%s\n", escape(c))
+		var keys []string
+		for k := range s[c] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(bw, "%s\t%s\t%s\n", escape(c), escape(k), escape(s[c][k]))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Read reads a snapshot written by Write.
+func Read(r io.Reader) (Snapshot, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	s := make(Snapshot)
+	sc := bufio.NewScanner(zr)
+	sc.Buffer(nil, 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "#") {
+			s.collecting(unescape(line[1:]))
+			continue
+		}
+		f := strings.SplitN(line, "\t", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("invalid baseline line: %q", line)
+		}
+		s.collecting(unescape(f[0]))[unescape(f[1])] = unescape(f[2])
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the snapshot to a file.
+func (s Snapshot) Save(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Change describes a difference between two snapshots.
+type Change struct {
+	Kind               string // "added", "removed", or "modified"
+	Category, Key      string
+	OldValue, NewValue string
+}
+
+// Compare returns the differences between an old and a new snapshot,
+// sorted by category and key. Categories that are missing from
+// either snapshot are not compared. For some categories (see
+// addedOnly), removed items are not reported.
+func Compare(old, new Snapshot) []Change {
+	var changes []Change
+	for c, nm := range new {
+		om, ok := old[c]
+		if !ok {
+			continue
+		}
+		for k, nv := range nm {
+			if ov, ok := om[k]; !ok {
+				changes = append(changes, Change{"added", c, k, "", nv})
+			} else if ov != nv {
+				changes = append(changes, Change{"modified", c, k, ov, nv})
+			}
+		}
+		if addedOnly[c] {
+			continue
+		}
+		for k, ov := range om {
+			if _, ok := nm[k]; !ok {
+				changes = append(changes, Change{"removed", c, k, ov, ""})
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Category != changes[j].Category {
+			return changes[i].Category < changes[j].Category
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
@@ -0,0 +1,73 @@
+package baseline
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	s := Snapshot{
+		"suid":   {"/usr/bin/passwd": "-rwsr-xr-x 0 0", "/tmp/a\tb": "x\ny"},
+		"listen": {},
+	}
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, r) {
+		t.Errorf("got %v, expected %v", r, s)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := Snapshot{
+		"suid":    {"/usr/bin/passwd": "a", "/usr/bin/su": "b"},
+		"listen":  {},
+		"autorun": {"x": "y"},
+	}
+	new := Snapshot{
+		"suid":    {"/usr/bin/passwd": "a", "/usr/bin/su": "c", "/tmp/sh": "d"},
+		"listen":  {"tcp 0.0.0.0:4444": "nc"},
+		"process": {"/tmp/x": ""},
+	}
+	expected := []Change{
+		{"added", "listen", "tcp 0.0.0.0:4444", "", "nc"},
+		{"added", "suid", "/tmp/sh", "", "d"},
+		{"modified", "suid", "/usr/bin/su", "b", "c"},
+	}
+	if got := Compare(old, new); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+	if got := Compare(new, old); len(got) != 3 || got[0].Kind != "removed" {
+		t.Errorf("reverse comparison: %+v", got)
+	}
+}
+
+func TestCompareAddedOnly(t *testing.T) {
+	old := Snapshot{"process": {"/tmp/x": "", "/opt/app/bin/app": ""}}
+	new := Snapshot{"process": {"/opt/app/bin/app": "", "/dev/shm/y": ""}}
+	expected := []Change{{"added", "process", "/dev/shm/y", "", ""}}
+	if got := Compare(old, new); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %+v, expected %+v", got, expected)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	mu.Lock()
+	current = Snapshot{"suid": {"/usr/bin/su": "a"}, "listen": {}}
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		current, incomplete = make(Snapshot), make(map[string]bool)
+		mu.Unlock()
+	}()
+	Incomplete("suid")
+	if got, expected := Current(), (Snapshot{"listen": {}}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,203 @@
+// Package checkpoint records the progress of a scan in a state file so
+// that an interrupted scan can be resumed.
+package checkpoint
+
+import (
+	"github.com/spyre-project/spyre/log"
+
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// saveInterval limits how often the state file is written while
+// walking the filesystem.
+const saveInterval = 10 * time.Second
+
+// State describes which parts of a scan have been completed. The file
+// walk is recorded as the last path that has been scanned below the
+// current root; since afero.Walk visits files in lexical order, every
+// path that comes before it in walk order has been scanned.
+//
+// All methods can be called on a nil *State; they do nothing in that
+// case.
+type State struct {
+	ConfigHash string    `json:"config_hash"`
+	Started    time.Time `json:"started"`
+	PhasesDone []string  `json:"phases_done,omitempty"`
+	EvtxDone   []string  `json:"evtx_done,omitempty"`
+	RootsDone  []string  `json:"roots_done,omitempty"`
+	Root       string    `json:"root,omitempty"`
+	LastPath   string    `json:"last_path,omitempty"`
+
+	path     string
+	lastSave time.Time
+}
+
+// New returns an empty state that will be written to path.
+func New(path, configHash string) *State {
+	return &State{ConfigHash: configHash, Started: time.Now(), path: path}
+}
+
+// Load reads a state file.
+func Load(path string) (*State, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return nil, err
+	}
+	if s.ConfigHash == "" {
+		return nil, errors.New("invalid state file")
+	}
+	s.path = path
+	return &s, nil
+}
+
+// Save writes the state file. The file is replaced atomically so that
+// an interruption does not leave a partially written state behind.
+func (s *State) Save() error {
+	if s == nil {
+		return nil
+	}
+	buf, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(buf); err == nil {
+		err = tmp.Sync()
+	}
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.lastSave = time.Now()
+	return nil
+}
+
+func (s *State) save() {
+	if err := s.Save(); err != nil {
+		log.Errorf("Could not write state file %s: %v", s.path, err)
+	}
+}
+
+// Remove deletes the state file after the scan has completed.
+func (s *State) Remove() error {
+	if s == nil {
+		return nil
+	}
+	return os.Remove(s.path)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PhaseDone reports whether a scan phase has been completed.
+func (s *State) PhaseDone(name string) bool { return s != nil && contains(s.PhasesDone, name) }
+
+// MarkPhase records a scan phase as completed.
+func (s *State) MarkPhase(name string) {
+	if s == nil {
+		return
+	}
+	s.PhasesDone = append(s.PhasesDone, name)
+	s.save()
+}
+
+// EvtxFileDone reports whether all events of an event log file have
+// been scanned.
+func (s *State) EvtxFileDone(path string) bool { return s != nil && contains(s.EvtxDone, path) }
+
+// MarkEvtxFile records an event log file as completely scanned.
+func (s *State) MarkEvtxFile(path string) {
+	if s == nil {
+		return
+	}
+	s.EvtxDone = append(s.EvtxDone, path)
+	s.save()
+}
+
+// RootDone reports whether a file scan root has been completely
+// walked.
+func (s *State) RootDone(root string) bool { return s != nil && contains(s.RootsDone, root) }
+
+// MarkRoot records a file scan root as completely walked.
+func (s *State) MarkRoot(root string) {
+	if s == nil {
+		return
+	}
+	s.RootsDone = append(s.RootsDone, root)
+	s.Root, s.LastPath = "", ""
+	s.save()
+}
+
+// MarkPath records that path below root has been scanned. The state
+// file is written at most every few seconds.
+func (s *State) MarkPath(root, path string) {
+	if s == nil {
+		return
+	}
+	s.Root, s.LastPath = root, path
+	if time.Since(s.lastSave) >= saveInterval {
+		s.save()
+	}
+}
+
+// SkipPath reports whether path below root has already been scanned
+// in a previous run. For directories, it reports whether the entire
+// subtree has been scanned.
+func (s *State) SkipPath(root, path string, isDir bool) bool {
+	if s == nil || s.Root != root || s.LastPath == "" {
+		return false
+	}
+	if isDir && isAncestor(path, s.LastPath) {
+		return false
+	}
+	return ComparePaths(path, s.LastPath) <= 0
+}
+
+func isAncestor(dir, path string) bool {
+	if dir == path {
+		return true
+	}
+	if !strings.HasSuffix(dir, string(filepath.Separator)) {
+		dir += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, dir)
+}
+
+// ComparePaths compares two paths in the order in which they are
+// visited by filepath.Walk: component by component, with directories
+// coming before their contents.
+func ComparePaths(a, b string) int {
+	ac := strings.Split(filepath.Clean(a), string(filepath.Separator))
+	bc := strings.Split(filepath.Clean(b), string(filepath.Separator))
+	for i := 0; i < len(ac) && i < len(bc); i++ {
+		if c := strings.Compare(ac[i], bc[i]); c != 0 {
+			return c
+		}
+	}
+	return len(ac) - len(bc)
+}
@@ -0,0 +1,84 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComparePaths(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"/a", "/a", 0},
+		{"/a", "/a/b", -1},
+		{"/a/b", "/a.b", -1},
+		{"/a.b", "/a/b", 1},
+		{"/usr/lib", "/usr/bin/ls", 1},
+		{"/etc/passwd", "/usr", -1},
+	} {
+		c := ComparePaths(filepath.FromSlash(test.a), filepath.FromSlash(test.b))
+		if (c < 0 && test.expected >= 0) || (c > 0 && test.expected <= 0) || (c == 0 && test.expected != 0) {
+			t.Errorf("ComparePaths(%s, %s): got %d, expected %d", test.a, test.b, c, test.expected)
+		}
+	}
+}
+
+func TestSkipPath(t *testing.T) {
+	s := &State{Root: "/", LastPath: filepath.FromSlash("/usr/lib/libc.so")}
+	for _, test := range []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"/etc", true, true},
+		{"/etc/passwd", false, true},
+		{"/usr", true, false},
+		{"/usr/lib", true, false},
+		{"/usr/bin", true, true},
+		{"/usr/lib/libc.so", false, true},
+		{"/usr/lib/libd.so", false, false},
+		{"/var", true, false},
+	} {
+		if got := s.SkipPath("/", filepath.FromSlash(test.path), test.isDir); got != test.expected {
+			t.Errorf("SkipPath(%s): got %v, expected %v", test.path, got, test.expected)
+		}
+	}
+	if s.SkipPath("/home", "/home/user", true) {
+		t.Errorf("path in a different root was skipped")
+	}
+	var nilState *State
+	if nilState.SkipPath("/", "/etc", true) || nilState.PhaseDone("file") {
+		t.Errorf("nil state skipped something")
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s := New(path, "hash")
+	s.MarkPhase("system")
+	s.MarkEvtxFile("System.evtx")
+	s.MarkPath("/", "/etc/passwd")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ConfigHash != "hash" || !l.PhaseDone("system") || l.PhaseDone("file") ||
+		!l.EvtxFileDone("System.evtx") || l.LastPath != "/etc/passwd" {
+		t.Errorf("unexpected state after load: %+v", l)
+	}
+	if err := l.Remove(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"github.com/shirou/gopsutil/v3/process"
+
+	"github.com/spyre-project/spyre/baseline"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/platform"
+	"github.com/spyre-project/spyre/report"
+
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// systemBinDirs returns the directories that contain executables
+// installed by the operating system or package manager.
+func systemBinDirs() []string {
+	if runtime.GOOS == "windows" {
+		var dirs []string
+		for _, v := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)"} {
+			if dir := os.Getenv(v); dir != "" {
+				dirs = append(dirs, dir)
+			}
+		}
+		return dirs
+	}
+	return []string{"/bin", "/sbin", "/usr/bin", "/usr/sbin", "/usr/lib", "/usr/libexec", "/lib", "/lib64"}
+}
+
+// unusualPath reports whether an executable is located outside the
+// system binary directories.
+func unusualPath(exe string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = strings.TrimRight(dir, `/\`)
+		if len(exe) > len(dir) && strings.EqualFold(exe[:len(dir)], dir) && strings.ContainsRune(`/\`, rune(exe[len(dir)])) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordProcess adds the executable of a running process to the
+// baseline if it is located outside the system binary directories.
+func recordProcess(pid int32) {
+	if !baseline.Enabled() {
+		return
+	}
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return
+	}
+	if exe, err := p.Exe(); err == nil && exe != "" && unusualPath(exe, systemBinDirs()) {
+		baseline.Record("process", exe, "")
+	}
+}
+
+// recordSUID adds setuid/setgid files to the baseline.
+func recordSUID(path string, info os.FileInfo) {
+	if info.Mode()&(os.ModeSetuid|os.ModeSetgid) == 0 {
+		return
+	}
+	uid, gid := platform.FileOwner(info)
+	baseline.Record("suid", path, fmt.Sprintf("mode=%s uid=%s gid=%s size=%d mtime=%s",
+		info.Mode(), uid, gid, info.Size(), report.FormatTime(info.ModTime())))
+}
+
+func openBaseline(path string) (io.ReadCloser, error) {
+	if f, err := os.Open(path); err == nil {
+		return f, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return config.Fs.Open(path)
+}
+
+// finishBaseline compares the items collected during the scan against
+// a previously saved baseline and/or saves them as a new baseline.
+func finishBaseline() (errors int) {
+	if config.BaselineCompare != "" {
+		if f, err := openBaseline(config.BaselineCompare); err != nil {
+			log.Errorf("Could not open baseline: %v", err)
+			errors++
+		} else {
+			old, err := baseline.Read(f)
+			f.Close()
+			if err != nil {
+				log.Errorf("Could not read baseline %s: %v", config.BaselineCompare, err)
+				errors++
+			} else {
+				reportDrift(baseline.Compare(old, baseline.Current()))
+			}
+		}
+	}
+	if config.BaselineSave != "" {
+		if err := baseline.Current().Save(config.BaselineSave); err != nil {
+			log.Errorf("Could not save baseline: %v", err)
+			errors++
+		} else {
+			log.Noticef("Saved baseline to %s", config.BaselineSave)
+		}
+	}
+	return
+}
+
+func reportDrift(changes []baseline.Change) {
+	log.Noticef("Found %d differences to baseline %s", len(changes), config.BaselineCompare)
+	for _, c := range changes {
+		var message string
+		switch c.Kind {
+		case "added":
+			message = fmt.Sprintf("New %s item since baseline: %s", c.Category, c.Key)
+		case "removed":
+			message = fmt.Sprintf("%s item removed since baseline: %s", c.Category, c.Key)
+		default:
+			message = fmt.Sprintf("%s item modified since baseline: %s", c.Category, c.Key)
+		}
+		report.AddSystemInfo("baseline_drift", message,
+			"rule", "baseline_"+c.Category+"_"+c.Kind,
+			"change", c.Kind,
+			"category", c.Category,
+			"key", c.Key,
+			"old_value", c.OldValue,
+			"new_value", c.NewValue,
+		)
+	}
+}
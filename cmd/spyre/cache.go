@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/filecache"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/platform"
+	"github.com/spyre-project/spyre/scanner"
+
+	"os"
+)
+
+// openFileCache opens the cache of files that have been scanned
+// without findings. It returns nil if caching is turned off.
+func openFileCache() *filecache.Cache {
+	if config.NoCache || config.CacheFile == "" {
+		return nil
+	}
+	c, err := filecache.Open(config.CacheFile, scanner.FileScanFingerprint(), config.CacheMaxEntries)
+	if err != nil {
+		log.Errorf("Could not read cache file %s, starting with an empty cache: %v", config.CacheFile, err)
+	} else {
+		log.Infof("Using cache file %s (%d entries)", config.CacheFile, c.Len())
+	}
+	return c
+}
+
+// fileCacheKey returns the cache key for a file. ok is false if the
+// file cannot be identified reliably on this platform.
+func fileCacheKey(path string, info os.FileInfo) (key filecache.Key, ok bool) {
+	dev, ino, ok := platform.FileID(path, info)
+	if !ok {
+		return
+	}
+	_, ctime, _ := platform.FileTimes(info)
+	return filecache.Key{
+		Dev:   dev,
+		Ino:   ino,
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+		Ctime: ctime.UnixNano(),
+	}, true
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/spf13/afero"
+	"github.com/spf13/pflag"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/hashset"
+	"github.com/spyre-project/spyre/log"
+
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+// hashsetCommand implements "spyre hashset", which converts known-good
+// hash lists (e.g. NSRLFile.txt) into the compact binary format that
+// is loaded quickly by --known-good.
+func hashsetCommand(args []string) int {
+	fs := pflag.NewFlagSet("hashset", pflag.ContinueOnError)
+	output := fs.StringP("output", "o", "", "output file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *output == "" {
+		fmt.Fprintln(os.Stderr, "usage: spyre hashset --output=FILE HASHLIST...")
+		return 2
+	}
+	var b hashset.Builder
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		s, err := hashset.Read(f)
+		f.Close()
+		if err == nil {
+			err = b.AddSet(s)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return 2
+		}
+	}
+	s := b.Set()
+	f, err := os.Create(*output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	w := bufio.NewWriter(f)
+	if _, err = s.WriteTo(w); err == nil {
+		err = w.Flush()
+	}
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *output, err)
+		os.Remove(*output)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d %s hashes to %s\n", s.Len(), s.Algorithm(), *output)
+	return 0
+}
+
+// openKnownGood reads the known-good hash set from the configuration
+// or, if it is not found there, from disk. It returns nil if no hash
+// set has been configured or it could not be read.
+func openKnownGood() *hashset.Set {
+	if config.KnownGood == "" {
+		return nil
+	}
+	f, err := config.Fs.Open(config.KnownGood)
+	if os.IsNotExist(err) {
+		f, err = afero.NewOsFs().Open(config.KnownGood)
+	}
+	if err != nil {
+		log.Errorf("Could not open known-good hash set: %v", err)
+		return nil
+	}
+	defer f.Close()
+	s, err := hashset.Read(f)
+	if err != nil {
+		log.Errorf("Could not read known-good hash set %s: %v", config.KnownGood, err)
+		return nil
+	}
+	log.Infof("Loaded %d known-good %s hashes from %s", s.Len(), s.Algorithm(), config.KnownGood)
+	return s
+}
+
+// isKnownGood hashes f and looks it up in the known-good hash set.
+// The file is rewound afterwards, so that it can be scanned.
+func isKnownGood(s *hashset.Set, f afero.File) bool {
+	ok, err := s.ContainsReader(f)
+	if _, serr := f.Seek(0, io.SeekStart); serr != nil && err == nil {
+		err = serr
+	}
+	if err != nil {
+		log.Debugf("Could not hash %s: %v", f.Name(), err)
+		return false
+	}
+	return ok
+}
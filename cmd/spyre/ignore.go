@@ -0,0 +1,39 @@
+package main
+
+import (
+	"github.com/spf13/afero"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/pathmatch"
+
+	"os"
+)
+
+// loadIgnoreList reads the path exclusion list from the configuration
+// and from disk (relative to the current working directory). Patterns
+// from both sources are combined.
+func loadIgnoreList() *pathmatch.Matcher {
+	m := &pathmatch.Matcher{}
+	for _, src := range []struct {
+		fs   afero.Fs
+		name string
+	}{
+		{config.Fs, "configuration"},
+		{afero.NewOsFs(), "disk"},
+	} {
+		f, err := src.fs.Open(config.IgnorePath)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Errorf("Could not open ignore list %s (%s): %v", config.IgnorePath, src.name, err)
+			}
+			continue
+		}
+		if err := m.Parse(f); err != nil {
+			log.Errorf("Could not parse ignore list %s (%s): %v", config.IgnorePath, src.name, err)
+		}
+		f.Close()
+	}
+	log.Debugf("Loaded %d path exclusion patterns", m.Len())
+	return m
+}
@@ -0,0 +1,107 @@
+// +build linux
+
+package main
+
+import (
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/platform"
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/scanner"
+
+	"fmt"
+	"os"
+	"strings"
+)
+
+// procFile is a file that backs a process. It may have been opened
+// through /proc, but is reported under the path recorded by the
+// kernel.
+type procFile struct {
+	*os.File
+	name string
+}
+
+func (f *procFile) Name() string { return f.name }
+
+type fileID struct{ dev, ino uint64 }
+
+// procFiles passes the executables and libraries of processes to the
+// file scan modules. Each file is scanned only once, even if it is
+// used by many processes.
+type procFiles struct {
+	proc procfs.FS
+	seen map[fileID]bool
+}
+
+func newProcFiles() *procFiles {
+	return &procFiles{proc: procfs.Default, seen: make(map[fileID]bool)}
+}
+
+// candidates returns the names of the executable and the mapped files
+// of a process, along with the paths through which they can be
+// opened. Files are opened through /proc, so that they are resolved
+// in the process's mount namespace (e.g. in a container); deleted
+// files can only be opened through map_files.
+func (pf *procFiles) candidates(pid int) (names, paths []string) {
+	p := pf.proc.Proc(pid)
+	if exe, err := p.Exe(); err == nil {
+		names, paths = append(names, exe), append(paths, p.Path("exe"))
+	}
+	maps, err := p.Maps()
+	if err != nil {
+		log.Debugf("Could not read memory maps of pid %d: %v", pid, err)
+		return
+	}
+	seen := make(map[string]bool)
+	for _, m := range maps {
+		if m.Inode == 0 || !strings.HasPrefix(m.Path, "/") || seen[m.Path] {
+			continue
+		}
+		seen[m.Path] = true
+		path := p.Path("root", m.Path)
+		if m.Deleted() {
+			path = p.Path("map_files", fmt.Sprintf("%x-%x", m.Start, m.End))
+		}
+		names, paths = append(names, m.Path), append(paths, path)
+	}
+	return
+}
+
+// scan scans the files backing a process and returns the number of
+// files scanned and the number of errors.
+func (pf *procFiles) scan(pid int) (scanned, errors int) {
+	names, paths := pf.candidates(pid)
+	for i, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			log.Debugf("Could not access %s of pid %d (%s): %v", names[i], pid, path, err)
+			continue
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if dev, ino, ok := platform.FileID(path, fi); ok {
+			id := fileID{dev, ino}
+			if pf.seen[id] {
+				continue
+			}
+			pf.seen[id] = true
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			log.Errorf("Could not open %s of pid %d: %v", names[i], pid, err)
+			errors++
+			continue
+		}
+		log.Debugf("Scanning %s of pid %d...", names[i], pid)
+		err = scanner.ScanFile(&procFile{File: f, name: names[i]})
+		f.Close()
+		if err != nil {
+			log.Errorf("Error scanning file: %s: %v", names[i], err)
+			errors++
+			continue
+		}
+		scanned++
+	}
+	return
+}
@@ -0,0 +1,17 @@
+// +build !linux
+
+package main
+
+import (
+	"github.com/spyre-project/spyre/log"
+)
+
+// procFiles is only implemented on Linux.
+type procFiles struct{}
+
+func newProcFiles() *procFiles {
+	log.Notice("Scanning process executables and libraries is not supported on this platform")
+	return &procFiles{}
+}
+
+func (*procFiles) scan(pid int) (scanned, errors int) { return 0, 0 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"github.com/spf13/afero"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/pathmatch"
+	"github.com/spyre-project/spyre/platform"
+	"github.com/spyre-project/spyre/progress"
+	"github.com/spyre-project/spyre/report"
+
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+var tracker = progress.NewTracker()
+
+// startProgress starts periodic progress messages and heartbeat
+// records, and installs a signal handler that dumps the current
+// status where supported.
+func startProgress() (stop func()) {
+	notifyStatusSignal()
+	if config.ProgressInterval <= 0 {
+		return func() {}
+	}
+	return tracker.Run(config.ProgressInterval, emitProgress)
+}
+
+func emitProgress(s progress.Status) {
+	log.Noticef("Progress: %s", s)
+	if !config.Heartbeat {
+		return
+	}
+	i64 := func(i int64) string { return strconv.FormatInt(i, 10) }
+	report.AddSystemInfo("heartbeat", "Scan in progress: "+s.Phase,
+		"phase", s.Phase,
+		"files_done", i64(s.Files),
+		"files_total", i64(s.TotalFiles),
+		"bytes_done", i64(s.Bytes),
+		"bytes_total", i64(s.TotalBytes),
+		"current_path", s.Current,
+		"elapsed", s.Elapsed.Round(time.Second).String(),
+		"eta", s.ETA.Round(time.Second).String(),
+	)
+}
+
+// estimateFiles estimates the number of files and bytes that will be
+// scanned below paths, according to the --progress-estimate setting.
+func estimateFiles(fs afero.Fs, paths []string, ignore *pathmatch.Matcher) (files, bytes int64) {
+	switch config.ProgressEstimate {
+	case "usage":
+		for _, path := range paths {
+			if used, err := platform.UsedSpace(path); err == nil {
+				bytes += int64(used)
+			} else {
+				log.Debugf("Could not determine filesystem usage for %s: %v", path, err)
+			}
+		}
+	case "walk":
+		log.Info("Counting files to be scanned...")
+		for _, path := range paths {
+			afero.Walk(fs, path, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if info.IsDir() {
+					if platform.SkipDir(fs, path) || ignore.Match(path, true) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if ignore.Match(path, false) {
+					return nil
+				}
+				const specialMode = os.ModeSymlink | os.ModeDevice | os.ModeNamedPipe | os.ModeSocket | os.ModeCharDevice
+				if info.Mode()&specialMode != 0 {
+					return nil
+				}
+				if int64(config.MaxFileSize) > 0 && info.Size() > int64(config.MaxFileSize) {
+					return nil
+				}
+				files++
+				bytes += info.Size()
+				return nil
+			})
+		}
+		log.Infof("Found %d files (%d bytes) to be scanned", files, bytes)
+	}
+	return
+}
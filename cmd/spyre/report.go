@@ -0,0 +1,152 @@
+package main
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/spyre-project/spyre/report"
+
+	"compress/gzip"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// reportCommand implements the "spyre report" subcommands that are
+// used by analysts to process collected reports.
+func reportCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: spyre report verify [--key=FILE] REPORT...")
+		fmt.Fprintln(os.Stderr, "       spyre report decrypt --key=FILE [--output=FILE] REPORT...")
+		return 2
+	}
+	switch args[0] {
+	case "verify":
+		return reportVerify(args[1:])
+	case "decrypt":
+		return reportDecrypt(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown report subcommand '%s'\n", args[0])
+		return 2
+	}
+}
+
+// openReports returns the concatenation of one or more report files
+// that make up a single report stream. Rotated files that have been
+// compressed are decompressed on the fly.
+func openReports(paths []string) (io.Reader, func(), error) {
+	var readers []io.Reader
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		if !strings.HasSuffix(path, ".gz") {
+			readers = append(readers, f)
+			continue
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		readers = append(readers, zr)
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
+
+func reportVerify(args []string) int {
+	fs := pflag.NewFlagSet("report verify", pflag.ContinueOnError)
+	keyFile := fs.String("key", "", "Ed25519 public key that the report trailer must be signed with")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: spyre report verify [--key=FILE] REPORT...")
+		return 2
+	}
+	var pub ed25519.PublicKey
+	if *keyFile != "" {
+		buf, err := ioutil.ReadFile(*keyFile)
+		if err == nil {
+			pub, err = report.ParsePublicKey(buf)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *keyFile, err)
+			return 2
+		}
+	}
+	r, closeAll, err := openReports(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer closeAll()
+	res, err := report.Verify(r, pub)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verification FAILED: %v\n", err)
+		return 1
+	}
+	fmt.Printf("OK: %d records in %d segment(s), %d signed\n", res.Records, res.Segments, res.Signed)
+	if res.Prev != "" {
+		fmt.Printf("first segment is linked to chain value %s\n", res.Prev)
+	}
+	fmt.Printf("last chain value: %s\n", res.Chain)
+	if pub == nil && res.Signed > 0 {
+		fmt.Println("warning: signatures were checked against the public key embedded in the report; use --key to pin the expected key")
+	}
+	return 0
+}
+
+func reportDecrypt(args []string) int {
+	fs := pflag.NewFlagSet("report decrypt", pflag.ContinueOnError)
+	keyFile := fs.String("key", "", "X25519 private key")
+	output := fs.StringP("output", "o", "-", "file to write decrypted report to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || *keyFile == "" {
+		fmt.Fprintln(os.Stderr, "usage: spyre report decrypt --key=FILE [--output=FILE] REPORT...")
+		return 2
+	}
+	buf, err := ioutil.ReadFile(*keyFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	key, err := report.ParseX25519Key(buf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *keyFile, err)
+		return 2
+	}
+	r, closeAll, err := openReports(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer closeAll()
+	var w io.WriteCloser = os.Stdout
+	if *output != "-" {
+		if w, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	err = report.Decrypt(w, r, key)
+	w.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "decryption FAILED: %v\n", err)
+		return 1
+	}
+	return 0
+}
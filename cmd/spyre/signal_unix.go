@@ -0,0 +1,23 @@
+// +build !windows
+
+package main
+
+import (
+	"github.com/spyre-project/spyre/log"
+
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyStatusSignal logs the current scan status whenever SIGUSR1 is
+// received.
+func notifyStatusSignal() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGUSR1)
+	go func() {
+		for range c {
+			log.Noticef("Status: %s", tracker.Status())
+		}
+	}()
+}
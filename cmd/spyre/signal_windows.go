@@ -0,0 +1,5 @@
+package main
+
+// notifyStatusSignal does nothing, since there is no SIGUSR1 on
+// Windows.
+func notifyStatusSignal() {}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/spyre-project/spyre"
 	"github.com/spyre-project/spyre/appendedzip"
+	"github.com/spyre-project/spyre/baseline"
 	"github.com/spyre-project/spyre/config"
 	"github.com/spyre-project/spyre/log"
 	"github.com/spyre-project/spyre/platform"
@@ -22,12 +23,24 @@ import (
 
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
-	"io/ioutil"
 	"strings"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		os.Exit(reportCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "hashset" {
+		os.Exit(hashsetCommand(os.Args[2:]))
+	}
+	os.Exit(run())
+}
+
+// run performs the scan and returns the process exit code: 0 if
+// nothing was found, 1 if there were findings, 2 if errors occurred.
+func run() int {
 	ourpid := os.Getpid()
 
 	log.Infof("This is Spyre version %s, pid=%d", spyre.Version, ourpid)
@@ -49,7 +62,7 @@ func main() {
 
 	if err := config.Init(); err != nil {
 		log.Errorf("Failed to parse configuration: %s", err)
-		os.Exit(1)
+		return exitErrors
 	}
 
 	if !config.HighPriority {
@@ -61,12 +74,18 @@ func main() {
 
 	if err := report.Init(); err != nil {
 		log.Errorf("Failed to initialize report target: %v", err)
-		os.Exit(1)
+		return exitErrors
 	}
 
 	if err := scanner.InitModules(); err != nil {
 		log.Errorf("Initialize: %v", err)
-		os.Exit(1)
+		return exitErrors
+	}
+
+	state, err := loadState()
+	if err != nil {
+		log.Errorf("%v", err)
+		return exitErrors
 	}
 
 	report.AddStringf("This is Spyre version %s, running on host %s, pid=%d",
@@ -76,32 +95,88 @@ func main() {
 	ts := time.Now().Format("2006-01-02 15:04:05.000 -0700 MST")
 	log.Infof("Scan started at %s", ts)
 	report.AddStringf("Scan started at %s", ts)
+	if config.TimeWindowSet() {
+		since, until := "(open)", "(open)"
+		if t := config.ModifiedSince.Time(); !t.IsZero() {
+			since = report.FormatTime(t)
+		}
+		if t := config.ModifiedUntil.Time(); !t.IsZero() {
+			until = report.FormatTime(t)
+		}
+		log.Infof("Only scanning files and events modified between %s and %s", since, until)
+		report.AddStringf("Time window: modified since %s, until %s", since, until)
+	}
+	if config.Resume && state != nil && len(state.PhasesDone)+len(state.RootsDone) > 0 {
+		reportResume(state)
+	}
+	summary := newScanSummary()
+	defer startProgress()()
 
-	if err := scanner.ScanSystem(); err != nil {
-		log.Errorf("Error scanning system:: %v", err)
+	tracker.SetPhase("system")
+	phase := summary.phase("system")
+	if state.PhaseDone("system") {
+		log.Notice("Skipping system scan (completed in previous run)")
+	} else {
+		if err := scanner.ScanSystem(); err != nil {
+			log.Errorf("Error scanning system:: %v", err)
+			phase.errors++
+		}
+		state.MarkPhase("system")
 	}
+	phase.done()
 
 	// process scan first
-	if config.BProcScan {
+	if config.BProcScan && state.PhaseDone("process") {
+		log.Notice("Skipping process scan (completed in previous run)")
+	} else if config.BProcScan {
+	  tracker.SetPhase("process")
+	  phase = summary.phase("process")
 	  procs, err := process.Pids()
 	  if err != nil {
 		  log.Errorf("Error while enumerating processes: %v", err)
+		  phase.errors++
 	  } else {
+		  tracker.SetEstimate(int64(len(procs)), 0)
+		  baseline.Collecting("process")
+		  var files *procFiles
+		  if config.ProcScanFiles {
+			  files = newProcFiles()
+		  }
 		  for _, proc := range procs {
+			  tracker.Done(0)
 			  if int(proc) == ourpid {
 				  log.Debugf("Skipping process spyre: %d.", proc)
+				  phase.skipped++
 			  	continue
 		  	}
 	  		log.Infof("Scanning process pid: %d...", proc)
+			  tracker.Begin("pid " + strconv.Itoa(int(proc)))
+			  recordProcess(proc)
+			  if files != nil {
+				  n, errs := files.scan(int(proc))
+				  phase.files += n
+				  phase.errors += errs
+			  }
   			if err := scanner.ScanProc(proc); err != nil {
 				  log.Errorf("Error scanning pid -> %d: %v", proc, err)
+				  phase.errors++
+				  continue
 			  }
+			  phase.scanned++
 		  }
+		  state.MarkPhase("process")
 	  }
+	  phase.done()
   }
 
+	tracker.SetPhase("evtx")
+	phase = summary.phase("evtx")
 	fse := afero.NewOsFs()
 	for _, path := range config.EvtxPaths {
+		if state.PhaseDone("evtx") {
+			log.Notice("Skipping evtx scan (completed in previous run)")
+			break
+		}
 		afero.Walk(fse, path, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
@@ -122,80 +197,168 @@ func main() {
 				log.Noticef("Skipping not evtx (sp) %s", path)
 				return nil
 			}
+			if t := config.ModifiedSince.Time(); !t.IsZero() && info.ModTime().Before(t) {
+				log.Noticef("Skipping %s (not modified since %s)", path, t)
+				return nil
+			}
+			if state.EvtxFileDone(path) {
+				log.Noticef("Skipping %s (completed in previous run)", path)
+				return nil
+			}
 			ef, err := evtx.OpenDirty(path)
 			if err != nil {
 				log.Errorf("Error open evtx file: %s: %v", path, err)
+				phase.errors++
 				return nil
 			}
 			log.Noticef("Scanning file %s", path)
+			tracker.Begin(path)
 			for e := range ef.FastEvents() {
 				if e != nil {
+					if t, err := e.GetTime(&evtx.SystemTimePath); err == nil && !config.InTimeWindow(t) {
+						phase.skipped++
+						continue
+					}
 					if err = scanner.ScanEvtx(string(evtx.ToJSON(e)), evtx.ToJSON(e)); err != nil {
 						log.Errorf("Error scanning file: %s: %v", path, err)
+						phase.errors++
+						continue
 					}
+					phase.scanned++
+					tracker.Done(0)
 				}
 			}
+			state.MarkEvtxFile(path)
 			return nil
 		})
 	}
+	if !state.PhaseDone("evtx") {
+		state.MarkPhase("evtx")
+	}
+	phase.done()
 
-  f, err := os.Open(config.IgnorePath)
-	var tmpdata []byte
-	if err == nil {
-	    tmpdata, _ = ioutil.ReadAll(f)
-  }
-	f.Close()
-	IgnorePathValue := strings.Split(string(tmpdata), "\n")
+	tracker.SetPhase("file")
+	phase = summary.phase("file")
+	ignore := loadIgnoreList()
 	fs := afero.NewOsFs()
+	tracker.SetEstimate(estimateFiles(fs, config.Paths, ignore))
+	fileCache := openFileCache()
+	knownGood := openKnownGood()
+	// Files that have been visited by a previous run are not
+	// walked again, so the SUID list would be incomplete.
+	if state != nil && (len(state.RootsDone) > 0 || state.LastPath != "") {
+		baseline.Incomplete("suid")
+	}
+	baseline.Collecting("suid")
 	log.Infof("Scan file: %s, pid=%d", spyre.Version, ourpid)
-	for _, path := range config.Paths {
-		log.Infof("Scan fs path: %s", path)
-		afero.Walk(fs, path, func(path string, info os.FileInfo, err error) error {
+	for _, root := range config.Paths {
+		if state.RootDone(root) {
+			log.Noticef("Skipping fs path %s (completed in previous run)", root)
+			continue
+		}
+		log.Infof("Scan fs path: %s", root)
+		afero.Walk(fs, root, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return nil
 			}
+			if state.SkipPath(root, path, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 			if info.IsDir() {
 				log.Infof("Scan directory: %s", path)
 				if platform.SkipDir(fs, path) {
 					log.Noticef("Skipping %s", path)
 					return filepath.SkipDir
 				}
+				if ignore.Match(path, true) {
+					log.Noticef("Skipping %s (ignore list)", path)
+					return filepath.SkipDir
+				}
 				return nil
 			}
-			if sliceContains(IgnorePathValue, path) {
+			defer state.MarkPath(root, path)
+			if ignore.Match(path, false) {
+				phase.skipped++
 				return nil
 			}
 			const specialMode = os.ModeSymlink | os.ModeDevice | os.ModeNamedPipe | os.ModeSocket | os.ModeCharDevice
 			if info.Mode()&specialMode != 0 {
 				return nil
 			}
+			recordSUID(path, info)
+			if _, ctime, _ := platform.FileTimes(info); !config.InTimeWindow(info.ModTime(), ctime) {
+				phase.skipped++
+				return nil
+			}
 			if int64(config.MaxFileSize) > 0 && info.Size() > int64(config.MaxFileSize) {
+				phase.skipped++
 				return nil
       }
+			key, cacheable := fileCacheKey(path, info)
+			if cacheable && !scanner.MustScan(path) && fileCache.Clean(key) {
+				log.Debugf("Skipping %s (unchanged since last scan)", path)
+				phase.cached++
+				return nil
+			}
 			f, err := fs.Open(path)
 			if err != nil {
 				log.Errorf("Could not open %s", path)
+				phase.errors++
 				return nil
 			}
 			defer f.Close()
+			tracker.Begin(path)
+			defer tracker.Done(info.Size())
+			if knownGood.Len() > 0 && info.Size() >= int64(config.KnownGoodMinSize) && !scanner.MustScan(path) {
+				phase.hashed++
+				if isKnownGood(knownGood, f) {
+					log.Debugf("Skipping %s (known good)", path)
+					phase.knownGood++
+					// Not cached: the file has not been scanned,
+					// and the hash set may change.
+					return nil
+				}
+			}
 			log.Debugf("Scanning %s...", path)
+			findings, suppressed := report.Findings(), report.Suppressed()
 			if err = scanner.ScanFile(f); err != nil {
 				log.Errorf("Error scanning file: %s: %v", path, err)
+				phase.errors++
+				return nil
+			}
+			phase.scanned++
+			// Files with suppressed findings are not cached, so that
+			// they are scanned again if the allowlist changes.
+			if cacheable && report.Findings() == findings && report.Suppressed() == suppressed {
+				fileCache.Add(key)
 			}
 			return nil
 		})
+		state.MarkRoot(root)
+	}
+	if err := scanner.FinishFileScan(); err != nil {
+		log.Errorf("Error finishing file scan: %v", err)
+		phase.errors++
 	}
+	if err := fileCache.Save(); err != nil {
+		log.Errorf("Could not write cache file %s: %v", config.CacheFile, err)
+	}
+	phase.done()
 
 	ts = time.Now().Format("2006-01-02 15:04:05.000 -0700 MST")
 	log.Infof("Scan finished at %s", ts)
 	report.AddStringf("Scan finished at %s", ts)
-}
-
-func sliceContains(arr []string, str string) bool {
-	for _, s := range arr {
-		if s == str {
-			return true
-		}
+	if baseline.Enabled() {
+		phase = summary.phase("baseline")
+		phase.errors += finishBaseline()
+		phase.done()
+	}
+	summary.emit()
+	if err := state.Remove(); err != nil {
+		log.Errorf("Could not remove state file: %v", err)
 	}
-	return false
+	return summary.exitCode()
 }
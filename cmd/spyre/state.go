@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/spyre-project/spyre/checkpoint"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadState sets up the state file if one has been configured. With
+// --resume, the state of a previous run is loaded; it is only used if
+// it was written with the same configuration and rules, so the scan
+// modules must have been initialized. A nil state is returned if no
+// state file is used.
+func loadState() (*checkpoint.State, error) {
+	if config.StateFile == "" {
+		if config.Resume {
+			log.Notice("--resume has no effect without --state-file")
+		}
+		return nil, nil
+	}
+	hash := config.Hash(scanner.FileScanFingerprint())
+	if config.Resume {
+		state, err := checkpoint.Load(config.StateFile)
+		switch {
+		case os.IsNotExist(err):
+			log.Noticef("State file %s not found, starting a new scan", config.StateFile)
+		case err != nil:
+			return nil, fmt.Errorf("Failed to read state file %s: %v", config.StateFile, err)
+		case state.ConfigHash != hash:
+			return nil, fmt.Errorf("State file %s was written using a different configuration or rules, refusing to resume", config.StateFile)
+		default:
+			log.Noticef("Resuming scan started at %s", state.Started)
+			return state, nil
+		}
+	}
+	state := checkpoint.New(config.StateFile, hash)
+	if err := state.Save(); err != nil {
+		return nil, fmt.Errorf("Failed to write state file: %v", err)
+	}
+	return state, nil
+}
+
+// reportResume adds a record to the report that marks where the
+// previous scan is continued.
+func reportResume(state *checkpoint.State) {
+	report.AddSystemInfo("scan_resumed",
+		fmt.Sprintf("Resuming scan started at %s", report.FormatTime(state.Started)),
+		"previous_start", report.FormatTime(state.Started),
+		"config_hash", state.ConfigHash,
+		"phases_done", strings.Join(state.PhasesDone, ","),
+		"roots_done", strings.Join(state.RootsDone, ","),
+		"resume_root", state.Root,
+		"resume_after", state.LastPath,
+	)
+}
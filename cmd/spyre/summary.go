@@ -0,0 +1,120 @@
+package main
+
+import (
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Exit codes
+const (
+	exitClean    = 0
+	exitFindings = 1
+	exitErrors   = 2
+)
+
+// phaseStats contains counters for a single scan phase (system,
+// process, evtx, file). Depending on the phase, items are processes,
+// event log records, or files. Items that have not been scanned
+// because they are unchanged since an earlier scan are counted as
+// cached. Files that have been hashed for a lookup in the known-good
+// hash set are counted as hashed, those that were found as knownGood.
+// Files scanned on behalf of processes are counted as files.
+type phaseStats struct {
+	name                             string
+	start                            time.Time
+	duration                         time.Duration
+	scanned, skipped, cached, errors int
+	hashed, knownGood, files         int
+}
+
+func (p *phaseStats) done() { p.duration = time.Since(p.start) }
+
+type scanSummary struct {
+	start  time.Time
+	phases []*phaseStats
+}
+
+func newScanSummary() *scanSummary { return &scanSummary{start: time.Now()} }
+
+// phase starts a new scan phase.
+func (s *scanSummary) phase(name string) *phaseStats {
+	p := &phaseStats{name: name, start: time.Now()}
+	s.phases = append(s.phases, p)
+	return p
+}
+
+func (s *scanSummary) errors() (n int) {
+	for _, p := range s.phases {
+		n += p.errors
+	}
+	return
+}
+
+func (s *scanSummary) exitCode() int {
+	switch {
+	case report.Findings() > 0:
+		return exitFindings
+	case s.errors() > 0:
+		return exitErrors
+	default:
+		return exitClean
+	}
+}
+
+// emit writes the scan summary record to the report and the log.
+func (s *scanSummary) emit() {
+	itoa := strconv.Itoa
+	extra := []string{
+		"duration_total", time.Since(s.start).Round(time.Millisecond).String(),
+		"findings", itoa(report.Findings()),
+		"suppressed", itoa(report.Suppressed()),
+		"errors", itoa(s.errors()),
+		"exit_code", itoa(s.exitCode()),
+	}
+	for _, p := range s.phases {
+		extra = append(extra,
+			"duration_"+p.name, p.duration.Round(time.Millisecond).String(),
+			p.name+"_scanned", itoa(p.scanned),
+			p.name+"_skipped", itoa(p.skipped),
+			p.name+"_errors", itoa(p.errors),
+		)
+		if p.cached > 0 {
+			extra = append(extra, p.name+"_cached", itoa(p.cached))
+		}
+		if p.files > 0 {
+			extra = append(extra, p.name+"_files_scanned", itoa(p.files))
+		}
+		if p.hashed > 0 {
+			extra = append(extra,
+				p.name+"_hashed", itoa(p.hashed),
+				p.name+"_known_good", itoa(p.knownGood),
+			)
+		}
+	}
+	for _, name := range scanner.ModuleNames() {
+		st := scanner.Stats(name)
+		extra = append(extra,
+			"module_"+name+"_scanned", itoa(st.Scanned),
+			"module_"+name+"_skipped", itoa(st.Skipped),
+			"module_"+name+"_errors", itoa(st.Errors),
+			"module_"+name+"_matches", itoa(st.Matches),
+		)
+	}
+	var top []string
+	for _, rc := range report.TopRules(10) {
+		top = append(top, fmt.Sprintf("%s:%d", rc.Rule, rc.Count))
+	}
+	extra = append(extra, "top_rules", strings.Join(top, "|"))
+	message := fmt.Sprintf("Scan summary: %d findings, %d errors", report.Findings(), s.errors())
+	if n := report.Suppressed(); n > 0 {
+		message += fmt.Sprintf(", %d suppressed by allowlist", n)
+	}
+	log.Notice(message)
+	report.AddSystemInfo("scan_summary", message, extra...)
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/spyre-project/spyre/log"
 
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
@@ -29,8 +31,43 @@ var (
 	ProcIgnoreList     simpleStringSlice
 	IocFiles           simpleStringSlice
 	IgnorePath         string = "ignorepath.txt"
+	ProgressInterval   = time.Minute
+	ProgressEstimate   = "usage"
+	Heartbeat          bool
+	StateFile          string
+	Resume             bool
+	NoCache            bool
+	CacheFile          string
+	CacheMaxEntries    = 1000000
+	BaselineSave       string
+	BaselineCompare    string
+	FimPaths           simpleStringSlice
+	FimBaseline        = "fim.baseline"
+	FimUpdate          bool
+	FimSignKey         string
+	FimVerifyKey       string
+	ModifiedSince      timeBound
+	ModifiedUntil      timeBound
+	Allowlist          = "allowlist.txt"
+	KnownGood          string
+	KnownGoodMinSize   = fileSize(16 * 1024)
+	ProcScanFiles      bool
+	ProcRegions        simpleStringSlice
+	ProcDump           string
+	ProcDumpDir        = "procdump"
+	ProcDumpMaxSize    = fileSize(512 * 1024 * 1024)
+	ProcDumpTotalSize  = fileSize(2 * 1024 * 1024 * 1024)
+	ProcInventory      bool
 )
 
+func defaultCacheFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "spyre", "filecache")
+}
+
 // Fs is the "filesystem" in which configuration and rules are found.
 // This can be provided through a ZIP file appended to the binary.
 var Fs afero.Fs
@@ -62,6 +99,59 @@ func Init() error {
 		"Scan only system FS with yara (only windows)")
 	pflag.Var(&ProcIgnoreList, "proc-ignore", "Names of processes to be ignored from scanning")
 	pflag.StringVar(&IgnorePath, "path-ignore", "ignorepath.txt", "file contains path to ignore")
+	pflag.DurationVar(&ProgressInterval, "progress-interval", time.Minute,
+		"interval for progress messages, turn off by setting to 0")
+	pflag.StringVar(&ProgressEstimate, "progress-estimate", "usage",
+		"how to estimate the amount of files to be scanned: usage (filesystem usage), walk (count files before scanning), none")
+	pflag.BoolVar(&Heartbeat, "heartbeat", false,
+		"add progress (heartbeat) records to the report")
+	pflag.StringVar(&StateFile, "state-file", "",
+		"file in which the scan progress is recorded, so that an interrupted scan can be resumed")
+	pflag.BoolVar(&Resume, "resume", false,
+		"resume the scan recorded in the state file")
+	pflag.BoolVar(&NoCache, "no-cache", false,
+		"scan all files, even if they have been scanned without findings before")
+	pflag.StringVar(&CacheFile, "cache-file", defaultCacheFile(),
+		"file in which files that have been scanned without findings are recorded")
+	pflag.IntVar(&CacheMaxEntries, "cache-max-entries", 1000000,
+		"maximum number of files recorded in the cache file")
+	pflag.StringVar(&BaselineSave, "baseline-save", "",
+		"save a baseline of the host (SUID files, listening sockets, autostart entries, process executables) to this file")
+	pflag.StringVar(&BaselineCompare, "baseline-compare", "",
+		"report differences to the baseline in this file")
+	pflag.Var(&FimPaths, "fim-paths", "paths whose files are checked against the file integrity baseline (default: none, FIM is disabled)")
+	pflag.StringVar(&FimBaseline, "fim-baseline", "fim.baseline",
+		"file integrity baseline, read from the configuration and written next to the program")
+	pflag.BoolVar(&FimUpdate, "fim-update", false,
+		"replace the file integrity baseline with the current state after reporting changes")
+	pflag.StringVar(&FimSignKey, "fim-sign-key", "",
+		"Ed25519 private key (from the configuration) used to sign a new file integrity baseline")
+	pflag.StringVar(&FimVerifyKey, "fim-verify-key", "",
+		"Ed25519 public key (from the configuration) that the file integrity baseline must be signed with")
+	pflag.Var(&ModifiedSince, "modified-since",
+		"only scan files and event log records modified at or after this date (e.g. 2021-03-03) or duration ago (e.g. 72h, 7d)")
+	pflag.Var(&ModifiedUntil, "modified-until",
+		"only scan files and event log records modified at or before this date or duration ago")
+	pflag.StringVar(&Allowlist, "allowlist", "allowlist.txt",
+		"file (from the configuration) listing known false positives that are not reported")
+	pflag.StringVar(&KnownGood, "known-good", "",
+		"hash set of known-good files that are not scanned (text or binary format)")
+	pflag.Var(&KnownGoodMinSize, "known-good-min-size",
+		"minimum size of files that are looked up in the known-good hash set")
+	pflag.BoolVar(&ProcScanFiles, "proc-scan-files", false,
+		"scan executables and libraries of running processes with the file scan modules (Linux only)")
+	pflag.Var(&ProcRegions, "proc-regions",
+		"scan process memory region by region, limited to regions matching all of all, exec, write, anon, file (Linux only)")
+	pflag.StringVar(&ProcDump, "proc-dump", "",
+		"dump memory of processes matched by YARA rules: matched (regions containing matches) or all (Linux only)")
+	pflag.StringVar(&ProcDumpDir, "proc-dump-dir", "procdump",
+		"directory that process memory dumps are written to")
+	pflag.Var(&ProcDumpMaxSize, "proc-dump-max-size",
+		"maximum amount of memory that is dumped per process")
+	pflag.Var(&ProcDumpTotalSize, "proc-dump-total-size",
+		"maximum amount of memory that is dumped during the scan")
+	pflag.BoolVar(&ProcInventory, "proc-inventory", false,
+		"record every running process in the report (Linux only)")
 	pflag.Var(&YaraFileRules, "yara-rule-files", "")
 	pflag.CommandLine.MarkHidden("yara-rule-files")
 	var args []string
@@ -47,3 +47,10 @@ func (f *fileSize) String() string {
 }
 
 func (f *fileSize) Type() string { return "" }
+
+// ParseFileSize parses a size specification such as "32MB" or "1.5G".
+func ParseFileSize(val string) (int64, error) {
+	var f fileSize
+	err := f.Set(val)
+	return int64(f), err
+}
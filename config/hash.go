@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/spf13/pflag"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// hashIgnoredFlags lists flags that do not affect what is scanned and
+// how findings are reported.
+var hashIgnoredFlags = map[string]bool{
+	"loglevel":          true,
+	"high-priority":     true,
+	"progress-interval": true,
+	"progress-estimate": true,
+	"heartbeat":         true,
+	"state-file":        true,
+	"resume":            true,
+	"no-cache":          true,
+	"cache-file":        true,
+	"cache-max-entries": true,
+	"baseline-save":     true,
+	"fim-update":        true,
+	"fim-sign-key":      true,
+}
+
+// Hash returns a hash over the effective configuration and the given
+// fingerprint of the rules in use (see scanner.FileScanFingerprint).
+// It is used to determine whether an interrupted scan can be resumed.
+func Hash(fingerprint string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "rules=%s\n", fingerprint)
+	pflag.VisitAll(func(f *pflag.Flag) {
+		if !hashIgnoredFlags[f.Name] {
+			fmt.Fprintf(h, "%s=%s\n", f.Name, f.Value)
+		}
+	})
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeBound is a point in time that can be given as an absolute date
+// ("2021-03-03", "2021-03-03 12:00", RFC3339) or as a duration
+// relative to the start of the program ("72h", "7d", "2w").
+type timeBound struct {
+	t    time.Time
+	spec string
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func parseDuration(val string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(val, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(val, suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(val)
+}
+
+func (b *timeBound) Set(val string) error {
+	if val == "" {
+		*b = timeBound{}
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, val, time.Local); err == nil {
+			*b = timeBound{t, val}
+			return nil
+		}
+	}
+	if d, err := parseDuration(val); err == nil && d >= 0 {
+		*b = timeBound{time.Now().Add(-d), val}
+		return nil
+	}
+	return errors.New("could not parse date or duration")
+}
+
+func (b *timeBound) String() string { return b.spec }
+
+func (b *timeBound) Type() string { return "" }
+
+// Time returns the point in time, or the zero time if unset.
+func (b *timeBound) Time() time.Time { return b.t }
+
+// TimeWindowSet reports whether --modified-since or --modified-until
+// have been given.
+func TimeWindowSet() bool { return !ModifiedSince.t.IsZero() || !ModifiedUntil.t.IsZero() }
+
+// InTimeWindow reports whether at least one of the given timestamps
+// lies within the window set by --modified-since and
+// --modified-until. Zero timestamps are ignored. If no window has been
+// set, it always returns true.
+func InTimeWindow(ts ...time.Time) bool {
+	if !TimeWindowSet() {
+		return true
+	}
+	for _, t := range ts {
+		if t.IsZero() {
+			continue
+		}
+		if !ModifiedSince.t.IsZero() && t.Before(ModifiedSince.t) {
+			continue
+		}
+		if !ModifiedUntil.t.IsZero() && t.After(ModifiedUntil.t) {
+			continue
+		}
+		return true
+	}
+	return false
+}
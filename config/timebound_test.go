@@ -0,0 +1,48 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeBound(t *testing.T) {
+	var b timeBound
+	if err := b.Set("2021-03-03"); err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2021, 3, 3, 0, 0, 0, 0, time.Local); !b.Time().Equal(expected) {
+		t.Errorf("got %v, expected %v", b.Time(), expected)
+	}
+	if err := b.Set("2021-03-03T12:00:00Z"); err != nil || !b.Time().Equal(time.Date(2021, 3, 3, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC3339: got %v, %v", b.Time(), err)
+	}
+	for spec, d := range map[string]time.Duration{"72h": 72 * time.Hour, "7d": 7 * 24 * time.Hour, "2w": 14 * 24 * time.Hour} {
+		if err := b.Set(spec); err != nil {
+			t.Errorf("%s: %v", spec, err)
+		} else if diff := time.Since(b.Time()) - d; diff < 0 || diff > time.Minute {
+			t.Errorf("%s: got %v", spec, b.Time())
+		}
+	}
+	if err := b.Set("yesterday"); err == nil {
+		t.Errorf("invalid value accepted")
+	}
+}
+
+func TestInTimeWindow(t *testing.T) {
+	defer func() { ModifiedSince, ModifiedUntil = timeBound{}, timeBound{} }()
+	now := time.Now()
+	if !InTimeWindow(now.Add(-1000 * time.Hour)) {
+		t.Errorf("no window set, but timestamp rejected")
+	}
+	ModifiedSince.Set("24h")
+	if InTimeWindow(now.Add(-48*time.Hour), time.Time{}) {
+		t.Errorf("old timestamp accepted")
+	}
+	if !InTimeWindow(now.Add(-48*time.Hour), now.Add(-time.Hour)) {
+		t.Errorf("recent ctime rejected")
+	}
+	ModifiedUntil.Set("2h")
+	if InTimeWindow(now.Add(-time.Hour)) {
+		t.Errorf("timestamp after window accepted")
+	}
+}
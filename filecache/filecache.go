@@ -0,0 +1,175 @@
+// Package filecache remembers files that have been scanned without
+// findings, so that unchanged files can be skipped in later scans.
+package filecache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const magic = "SPYRECACHE1\n"
+
+// Key identifies a version of a file. If any of its fields changes,
+// the file is considered modified.
+type Key struct {
+	Dev, Ino     uint64
+	Size         int64
+	Mtime, Ctime int64
+}
+
+type entry struct {
+	ruleset [8]byte
+	used    int64
+}
+
+// Cache maps file keys to the ruleset under which the files have last
+// been scanned without findings. All methods can be called on a nil
+// *Cache; in that case, no file is considered clean.
+type Cache struct {
+	path       string
+	ruleset    [8]byte
+	maxEntries int
+	now        int64
+	entries    map[Key]entry
+}
+
+func rulesetID(ruleset string) (id [8]byte) {
+	sum := sha256.Sum256([]byte(ruleset))
+	copy(id[:], sum[:])
+	return
+}
+
+// Open reads the cache file at path. A missing file results in an
+// empty cache. If the file cannot be parsed, an empty cache is
+// returned along with the error. ruleset identifies the current
+// scan rules; maxEntries limits the number of entries that are
+// written back.
+func Open(path, ruleset string, maxEntries int) (*Cache, error) {
+	c := &Cache{
+		path:       path,
+		ruleset:    rulesetID(ruleset),
+		maxEntries: maxEntries,
+		now:        time.Now().Unix(),
+		entries:    make(map[Key]entry),
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	defer f.Close()
+	if err := c.read(bufio.NewReader(f)); err != nil {
+		c.entries = make(map[Key]entry)
+		return c, err
+	}
+	return c, nil
+}
+
+type record struct {
+	Key
+	Ruleset [8]byte
+	Used    int64
+}
+
+func (c *Cache) read(r io.Reader) error {
+	hdr := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, hdr); err != nil || string(hdr) != magic {
+		return errors.New("not a cache file")
+	}
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		var rec record
+		if err := binary.Read(r, binary.LittleEndian, &rec); err != nil {
+			return err
+		}
+		c.entries[rec.Key] = entry{rec.Ruleset, rec.Used}
+	}
+	return nil
+}
+
+// Clean reports whether the file identified by k has been scanned
+// without findings using the current ruleset.
+func (c *Cache) Clean(k Key) bool {
+	if c == nil {
+		return false
+	}
+	e, ok := c.entries[k]
+	if !ok || e.ruleset != c.ruleset {
+		return false
+	}
+	e.used = c.now
+	c.entries[k] = e
+	return true
+}
+
+// Add records that the file identified by k has been scanned without
+// findings using the current ruleset.
+func (c *Cache) Add(k Key) {
+	if c == nil {
+		return
+	}
+	c.entries[k] = entry{c.ruleset, c.now}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.entries)
+}
+
+// Save writes the cache file. Entries recorded under a different
+// ruleset are dropped; if there are more than maxEntries entries, the
+// least recently used ones are dropped.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	var recs []record
+	for k, e := range c.entries {
+		if e.ruleset == c.ruleset {
+			recs = append(recs, record{k, e.ruleset, e.used})
+		}
+	}
+	if c.maxEntries > 0 && len(recs) > c.maxEntries {
+		sort.Slice(recs, func(i, j int) bool { return recs[i].Used > recs[j].Used })
+		recs = recs[:c.maxEntries]
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	w.WriteString(magic)
+	binary.Write(w, binary.LittleEndian, uint64(len(recs)))
+	for _, rec := range recs {
+		binary.Write(w, binary.LittleEndian, &rec)
+	}
+	err = w.Flush()
+	if e := tmp.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package filecache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-filecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sub", "cache")
+
+	c, err := Open(path, "rules-1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k1 := Key{Dev: 1, Ino: 2, Size: 3, Mtime: 4, Ctime: 5}
+	k2 := Key{Dev: 1, Ino: 3, Size: 3, Mtime: 4, Ctime: 5}
+	k3 := Key{Dev: 1, Ino: 4, Size: 3, Mtime: 4, Ctime: 5}
+	if c.Clean(k1) {
+		t.Error("empty cache reported file as clean")
+	}
+	c.Add(k1)
+	c.Add(k2)
+	c.Add(k3)
+	c.entries[k1] = entry{c.ruleset, c.now - 100}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = Open(path, "rules-1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 || c.Clean(k1) || !c.Clean(k2) || !c.Clean(k3) {
+		t.Errorf("unexpected cache contents after reload: %+v", c.entries)
+	}
+	modified := k2
+	modified.Mtime++
+	if c.Clean(modified) {
+		t.Error("modified file reported as clean")
+	}
+
+	c, err = Open(path, "rules-2", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Clean(k2) {
+		t.Error("file reported as clean after ruleset change")
+	}
+
+	var nilCache *Cache
+	if nilCache.Clean(k2) || nilCache.Save() != nil {
+		t.Error("nil cache misbehaves")
+	}
+
+	ioutil.WriteFile(path, []byte("garbage"), 0600)
+	if c, err := Open(path, "rules-1", 2); err == nil || c == nil || c.Len() != 0 {
+		t.Errorf("corrupt cache file: %v", err)
+	}
+}
@@ -12,7 +12,7 @@ require (
 	github.com/prometheus/common v0.15.0
 	github.com/spf13/afero v1.5.1
 	github.com/spf13/pflag v1.0.5
-	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad  // indirect
+	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
 	golang.org/x/sys v0.0.0-20210113181707-4bcb84eeeb78
 	golang.org/x/text v0.3.5 // indirect
 	www.velocidex.com/golang/regparser v0.0.0-20200428153047-c2d019c325d7 // indirect
@@ -0,0 +1,286 @@
+// Package hashset implements sets of known-good file hashes, e.g. from
+// the NIST National Software Reference Library (NSRL), that are used
+// to skip scanning of trusted files.
+//
+// Sets are read from text files (one hash per line, the output of
+// tools such as sha256sum, or NSRL-style CSV files with a header line)
+// or from a compact binary format that contains the sorted hashes.
+package hashset
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+)
+
+const magic = "SPYREHASHSET1\n"
+
+// maxPrealloc limits the memory that is allocated for a binary set
+// based on the hash count in its header. Larger sets are read into a
+// growing buffer, so that a corrupt count cannot exhaust memory.
+const maxPrealloc = 64 * 1024 * 1024
+
+// Set is a sorted set of hashes of the same algorithm (MD5, SHA-1, or
+// SHA-256). All methods can be called on a nil *Set; it is empty.
+type Set struct {
+	size   int
+	hashes []byte
+}
+
+// Len returns the number of hashes in the set.
+func (s *Set) Len() int {
+	if s == nil || s.size == 0 {
+		return 0
+	}
+	return len(s.hashes) / s.size
+}
+
+// Algorithm returns the name of the hash algorithm used by the set.
+func (s *Set) Algorithm() string {
+	if s == nil {
+		return ""
+	}
+	switch s.size {
+	case md5.Size:
+		return "MD5"
+	case sha1.Size:
+		return "SHA-1"
+	case sha256.Size:
+		return "SHA-256"
+	}
+	return ""
+}
+
+func (s *Set) at(i int) []byte { return s.hashes[i*s.size : (i+1)*s.size] }
+
+// Contains reports whether sum is in the set.
+func (s *Set) Contains(sum []byte) bool {
+	if s.Len() == 0 || len(sum) != s.size {
+		return false
+	}
+	n := s.Len()
+	i := sort.Search(n, func(i int) bool { return bytes.Compare(s.at(i), sum) >= 0 })
+	return i < n && bytes.Equal(s.at(i), sum)
+}
+
+func newHash(size int) hash.Hash {
+	switch size {
+	case md5.Size:
+		return md5.New()
+	case sha1.Size:
+		return sha1.New()
+	case sha256.Size:
+		return sha256.New()
+	}
+	return nil
+}
+
+// ContainsReader hashes the contents of r and reports whether the
+// hash is in the set.
+func (s *Set) ContainsReader(r io.Reader) (bool, error) {
+	if s.Len() == 0 {
+		return false, nil
+	}
+	h := newHash(s.size)
+	if _, err := io.Copy(h, r); err != nil {
+		return false, err
+	}
+	return s.Contains(h.Sum(nil)), nil
+}
+
+// WriteTo writes the set in binary format.
+func (s *Set) WriteTo(w io.Writer) (int64, error) {
+	var hdr [len(magic) + 1 + 8]byte
+	copy(hdr[:], magic)
+	hdr[len(magic)] = byte(s.size)
+	binary.BigEndian.PutUint64(hdr[len(magic)+1:], uint64(s.Len()))
+	n, err := w.Write(hdr[:])
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(s.hashes)
+	return int64(n + m), err
+}
+
+// Builder collects hashes for a Set.
+type Builder struct {
+	size   int
+	hashes []byte
+}
+
+// Add adds a hash to the set. All hashes must have the same size.
+func (b *Builder) Add(sum []byte) error {
+	if newHash(len(sum)) == nil {
+		return fmt.Errorf("unsupported hash size %d", len(sum))
+	}
+	if b.size == 0 {
+		b.size = len(sum)
+	} else if len(sum) != b.size {
+		return fmt.Errorf("hash size %d does not match earlier hashes (%d)", len(sum), b.size)
+	}
+	b.hashes = append(b.hashes, sum...)
+	return nil
+}
+
+// AddSet adds all hashes of s.
+func (b *Builder) AddSet(s *Set) error {
+	for i := 0; i < s.Len(); i++ {
+		if err := b.Add(s.at(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sortable struct {
+	size int
+	buf  []byte
+	tmp  []byte
+}
+
+func (s *sortable) Len() int { return len(s.buf) / s.size }
+func (s *sortable) Less(i, j int) bool {
+	return bytes.Compare(s.buf[i*s.size:(i+1)*s.size], s.buf[j*s.size:(j+1)*s.size]) < 0
+}
+func (s *sortable) Swap(i, j int) {
+	a, b := s.buf[i*s.size:(i+1)*s.size], s.buf[j*s.size:(j+1)*s.size]
+	copy(s.tmp, a)
+	copy(a, b)
+	copy(b, s.tmp)
+}
+
+// Set returns the sorted and deduplicated set of hashes that have
+// been added.
+func (b *Builder) Set() *Set {
+	if b.size == 0 {
+		return &Set{}
+	}
+	sort.Sort(&sortable{size: b.size, buf: b.hashes, tmp: make([]byte, b.size)})
+	out := b.hashes[:0]
+	for i := 0; i < len(b.hashes); i += b.size {
+		h := b.hashes[i : i+b.size]
+		if len(out) >= b.size && bytes.Equal(out[len(out)-b.size:], h) {
+			continue
+		}
+		out = append(out, h...)
+	}
+	b.hashes = nil
+	return &Set{size: b.size, hashes: out}
+}
+
+// Read reads a set in binary or text format.
+func Read(r io.Reader) (*Set, error) {
+	br := bufio.NewReader(r)
+	if buf, _ := br.Peek(len(magic)); string(buf) == magic {
+		return readBinary(br)
+	}
+	var b Builder
+	if err := b.ReadText(br); err != nil {
+		return nil, err
+	}
+	return b.Set(), nil
+}
+
+func readBinary(r io.Reader) (*Set, error) {
+	var hdr [len(magic) + 1 + 8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	size := int(hdr[len(magic)])
+	if newHash(size) == nil {
+		return nil, fmt.Errorf("unsupported hash size %d", size)
+	}
+	count := binary.BigEndian.Uint64(hdr[len(magic)+1:])
+	if count > uint64(int(^uint(0)>>1)/size) {
+		return nil, errors.New("invalid hash count")
+	}
+	n := int64(count) * int64(size)
+	prealloc := n
+	if prealloc > maxPrealloc {
+		prealloc = maxPrealloc
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, prealloc))
+	if _, err := buf.ReadFrom(io.LimitReader(r, n)); err != nil {
+		return nil, err
+	}
+	if int64(buf.Len()) != n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	s := &Set{size: size, hashes: buf.Bytes()}
+	for i := 1; i < s.Len(); i++ {
+		if bytes.Compare(s.at(i-1), s.at(i)) >= 0 {
+			return nil, errors.New("hashes are not sorted")
+		}
+	}
+	return s, nil
+}
+
+// hashColumns lists the column names in NSRL-style header lines, in
+// order of preference.
+var hashColumns = []string{"SHA-256", "SHA256", "SHA-1", "SHA1", "MD5"}
+
+func splitFields(line string) []string {
+	fields := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	})
+	for i := range fields {
+		fields[i] = strings.Trim(fields[i], `"`)
+	}
+	return fields
+}
+
+// ReadText adds hashes from a text file. Empty lines and lines starting
+// with # are ignored. If the first line is a header line naming hash
+// columns (as in NSRLFile.txt), hashes are taken from the preferred
+// column; otherwise, the first field of each line is used.
+func (b *Builder) ReadText(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	column := -1
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		fields := splitFields(line)
+		if column < 0 {
+			column = 0
+			if idx := headerColumn(fields); idx >= 0 {
+				column = idx
+				continue
+			}
+		}
+		if column >= len(fields) {
+			return fmt.Errorf("line %d: missing hash column", lineno)
+		}
+		sum, err := hex.DecodeString(fields[column])
+		if err != nil {
+			return fmt.Errorf("line %d: invalid hash: %v", lineno, err)
+		}
+		if err := b.Add(sum); err != nil {
+			return fmt.Errorf("line %d: %v", lineno, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func headerColumn(fields []string) int {
+	for _, name := range hashColumns {
+		for i, f := range fields {
+			if strings.EqualFold(f, name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
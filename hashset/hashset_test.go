@@ -0,0 +1,100 @@
+package hashset
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const nsrl = `"SHA-1","MD5","CRC32","FileName","FileSize","ProductCode","OpSystemCode","SpecialCode"
+"0000004DA6391F7F5D2F7FCCF36CEBDA60C6EA02","0E53C14A3E48D94FF596A2824307B492","AA6A7B16","00br2026.gif",2226,228,"WIN",""
+"DA39A3EE5E6B4B0D3255BFEF95601890AFD80709","D41D8CD98F00B204E9800998ECF8427E","00000000","empty",0,1,"WIN",""
+"0000004DA6391F7F5D2F7FCCF36CEBDA60C6EA02","0E53C14A3E48D94FF596A2824307B492","AA6A7B16","copy.gif",2226,228,"WIN",""
+`
+
+func TestText(t *testing.T) {
+	s, err := Read(strings.NewReader(nsrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 2 || s.Algorithm() != "SHA-1" {
+		t.Fatalf("got %d %s hashes, expected 2 SHA-1", s.Len(), s.Algorithm())
+	}
+	if ok, err := s.ContainsReader(strings.NewReader("")); err != nil || !ok {
+		t.Errorf("empty file: got %v, %v", ok, err)
+	}
+	if ok, _ := s.ContainsReader(strings.NewReader("x")); ok {
+		t.Error("unexpected match")
+	}
+
+	s, err = Read(strings.NewReader(
+		"# sha256sum output\n" +
+			"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  /tmp/empty\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 1 || s.Algorithm() != "SHA-256" {
+		t.Fatalf("got %d %s hashes, expected 1 SHA-256", s.Len(), s.Algorithm())
+	}
+}
+
+func TestTextInvalid(t *testing.T) {
+	for _, text := range []string{
+		"xyz\n",
+		"abcd\n",
+		"d41d8cd98f00b204e9800998ecf8427e\nda39a3ee5e6b4b0d3255bfef95601890afd80709\n",
+	} {
+		if _, err := Read(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: expected error", text)
+		}
+	}
+}
+
+func TestBinary(t *testing.T) {
+	var b Builder
+	for _, s := range []string{"c", "a", "b", "a"} {
+		sum := sha1.Sum([]byte(s))
+		b.Add(sum[:])
+	}
+	s := b.Set()
+	if s.Len() != 3 {
+		t.Fatalf("got %d hashes, expected 3", s.Len())
+	}
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s2, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{"a", "b", "c"} {
+		sum := sha1.Sum([]byte(x))
+		if !s2.Contains(sum[:]) {
+			t.Errorf("%s (%s) not found", x, hex.EncodeToString(sum[:]))
+		}
+	}
+	sum := sha1.Sum([]byte("d"))
+	if s2.Contains(sum[:]) {
+		t.Error("unexpected match")
+	}
+
+	// Truncated file
+	if _, err := Read(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Error("truncated: expected error")
+	}
+
+	// Corrupt count in header
+	corrupt := append([]byte{}, buf.Bytes()...)
+	copy(corrupt[len(magic)+1:], []byte{0, 0, 1, 0, 0, 0, 0, 0})
+	if _, err := Read(bytes.NewReader(corrupt)); err == nil {
+		t.Error("corrupt count: expected error")
+	}
+
+	var nilSet *Set
+	if nilSet.Contains(sum[:]) || nilSet.Len() != 0 {
+		t.Error("nil set not empty")
+	}
+}
@@ -7,4 +7,5 @@ import (
   _ "github.com/spyre-project/spyre/scanner/yara"
   _ "github.com/spyre-project/spyre/scanner/command"
   _ "github.com/spyre-project/spyre/scanner/connect"
+  _ "github.com/spyre-project/spyre/scanner/fim"
 )
@@ -0,0 +1,3 @@
+package config
+
+import _ "github.com/spyre-project/spyre/scanner/procanomaly"
@@ -0,0 +1,175 @@
+// Package pathmatch implements path exclusion lists with
+// gitignore-style glob patterns and regular expressions.
+//
+// Each line of an exclusion list contains one pattern:
+//
+//   - Empty lines and lines starting with "#" are ignored.
+//   - "re:REGEX" matches the full path against a regular expression.
+//   - Other lines are glob patterns: "*" and "?" match within a path
+//     component, "**" matches across components, "[...]" matches a
+//     character class. Patterns that do not contain a "/" match the
+//     file or directory name at any level (e.g. "*.log",
+//     "node_modules"); patterns starting with "/" (or a drive letter
+//     on Windows) match the full path; other patterns match a
+//     trailing part of the path.
+//   - A trailing "/" restricts the pattern to directories.
+//   - A leading "!" re-includes paths excluded by earlier patterns.
+//
+// A pattern that matches a directory also matches everything below
+// it, so that the directory can be skipped altogether.
+package pathmatch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher matches paths against an exclusion list. The zero value
+// matches nothing.
+type Matcher struct {
+	rules []rule
+}
+
+// Parse reads patterns from r and adds them to m.
+func (m *Matcher) Parse(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := m.Add(line); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return sc.Err()
+}
+
+// Add adds a single pattern.
+func (m *Matcher) Add(pattern string) error {
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasPrefix(pattern, "re:") {
+		re, err := regexp.Compile(pattern[3:])
+		if err != nil {
+			return err
+		}
+		r.re = re
+		m.rules = append(m.rules, r)
+		return nil
+	}
+	pattern = normalize(strings.TrimSpace(pattern))
+	if strings.HasSuffix(pattern, "/") && len(pattern) > 1 {
+		r.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	var prefix string
+	switch {
+	case strings.HasPrefix(pattern, "/") || hasDriveLetter(pattern):
+		prefix = "^"
+	default:
+		prefix = "(?:^|/)"
+	}
+	re, err := regexp.Compile(prefix + globToRegexp(pattern) + "(/.*)?$")
+	if err != nil {
+		return err
+	}
+	r.re = re
+	m.rules = append(m.rules, r)
+	return nil
+}
+
+func hasDriveLetter(p string) bool {
+	return len(p) >= 2 && p[1] == ':' &&
+		('a' <= p[0] && p[0] <= 'z' || 'A' <= p[0] && p[0] <= 'Z')
+}
+
+// normalize converts paths and patterns to forward slashes. On
+// Windows, matching is case-insensitive.
+func normalize(p string) string {
+	if runtime.GOOS == "windows" {
+		return strings.ToLower(filepath.ToSlash(p))
+	}
+	return p
+}
+
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			if j := strings.IndexByte(glob[i+1:], ']'); j >= 0 {
+				class := glob[i+1 : i+1+j]
+				if strings.HasPrefix(class, "!") {
+					class = "^" + class[1:]
+				}
+				sb.WriteString("[" + class + "]")
+				i += j + 1
+			} else {
+				sb.WriteString(`\[`)
+			}
+		case c == '\\' && runtime.GOOS != "windows" && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Match reports whether path is excluded. isDir must be set if path
+// refers to a directory.
+func (m *Matcher) Match(path string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	p := normalize(path)
+	var excluded bool
+	for _, r := range m.rules {
+		sm := r.re.FindStringSubmatchIndex(p)
+		if sm == nil {
+			continue
+		}
+		// For glob rules, the submatch is non-empty if the pattern
+		// matched a parent directory of path.
+		if r.dirOnly && !isDir && (len(sm) < 4 || sm[2] < 0) {
+			continue
+		}
+		excluded = !r.negate
+	}
+	return excluded
+}
+
+// Len returns the number of patterns.
+func (m *Matcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.rules)
+}
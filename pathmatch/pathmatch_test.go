@@ -0,0 +1,62 @@
+package pathmatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	var m Matcher
+	err := m.Parse(strings.NewReader(`# comment
+
+/var/lib/docker
+*.log
+!/var/log/keep.log
+cache/
+/home/*/.cache/**
+re:^/srv/data/[0-9]+\.bin$
+/opt/file[12].txt
+exact/path
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"/var/lib/docker", true, true},
+		{"/var/lib/docker/overlay2/x", false, true},
+		{"/var/lib/dockerd", true, false},
+		{"/var/log/syslog.log", false, true},
+		{"/var/log/keep.log", false, false},
+		{"/tmp/cache", true, true},
+		{"/tmp/cache", false, false},
+		{"/tmp/cache/x", false, true},
+		{"/home/user/.cache/x/y", false, true},
+		{"/home/user/.cachex", false, false},
+		{"/srv/data/123.bin", false, true},
+		{"/srv/data/12a.bin", false, false},
+		{"/opt/file1.txt", false, true},
+		{"/opt/file3.txt", false, false},
+		{"/a/exact/path", false, true},
+		{"/a/inexact/path", false, false},
+		{"/etc/passwd", false, false},
+	} {
+		if got := m.Match(test.path, test.isDir); got != test.expected {
+			t.Errorf("Match(%s, %v): got %v, expected %v", test.path, test.isDir, got, test.expected)
+		}
+	}
+	var nilMatcher *Matcher
+	if nilMatcher.Match("/etc", true) {
+		t.Errorf("nil matcher matched")
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	var m Matcher
+	if err := m.Parse(strings.NewReader("re:(unclosed\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected error for invalid regex, got %v", err)
+	}
+}
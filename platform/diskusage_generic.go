@@ -0,0 +1,12 @@
+// +build !linux,!windows
+
+package platform
+
+import (
+	"errors"
+)
+
+// UsedSpace is not implemented on this platform.
+func UsedSpace(path string) (uint64, error) {
+	return 0, errors.New("not implemented")
+}
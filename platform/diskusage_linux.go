@@ -0,0 +1,15 @@
+package platform
+
+import (
+	"syscall"
+)
+
+// UsedSpace returns the number of bytes in use on the filesystem
+// that contains path.
+func UsedSpace(path string) (uint64, error) {
+	var buf syscall.Statfs_t
+	if err := syscall.Statfs(path, &buf); err != nil {
+		return 0, err
+	}
+	return (buf.Blocks - buf.Bfree) * uint64(buf.Bsize), nil
+}
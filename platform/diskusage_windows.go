@@ -0,0 +1,19 @@
+package platform
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// UsedSpace returns the number of bytes in use on the volume that
+// contains path.
+func UsedSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var avail, total, free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, &total, &free); err != nil {
+		return 0, err
+	}
+	return total - free, nil
+}
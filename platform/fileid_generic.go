@@ -0,0 +1,12 @@
+// +build !linux,!windows
+
+package platform
+
+import (
+	"os"
+)
+
+// FileID is not implemented on this platform.
+func FileID(path string, fi os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
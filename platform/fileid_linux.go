@@ -0,0 +1,15 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileID returns the device and inode numbers that identify a file.
+func FileID(path string, fi os.FileInfo) (dev, ino uint64, ok bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), true
+}
@@ -0,0 +1,19 @@
+// +build !windows
+
+package platform
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// FileOwner returns the numeric user and group IDs of a file's
+// owner, or empty strings if they are not available.
+func FileOwner(fi os.FileInfo) (uid, gid string) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ""
+	}
+	return strconv.FormatUint(uint64(st.Uid), 10), strconv.FormatUint(uint64(st.Gid), 10)
+}
@@ -0,0 +1,9 @@
+package platform
+
+import (
+	"os"
+)
+
+// FileOwner is not implemented on Windows; file ownership is
+// described by security descriptors.
+func FileOwner(fi os.FileInfo) (uid, gid string) { return "", "" }
@@ -0,0 +1,15 @@
+// +build !linux,!windows
+
+package platform
+
+import (
+	"os"
+	"time"
+)
+
+// FileTimes returns the last access, inode change and birth times
+// for a file, as far as they are available on the current
+// platform. Unavailable timestamps are returned as the zero time.
+func FileTimes(fi os.FileInfo) (atime, ctime, btime time.Time) {
+	return
+}
@@ -0,0 +1,20 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileTimes returns the last access, inode change and birth times
+// for a file, as far as they are available on the current
+// platform. Unavailable timestamps are returned as the zero time.
+func FileTimes(fi os.FileInfo) (atime, ctime, btime time.Time) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	atime = time.Unix(int64(st.Atim.Sec), int64(st.Atim.Nsec))
+	ctime = time.Unix(int64(st.Ctim.Sec), int64(st.Ctim.Nsec))
+	return
+}
@@ -0,0 +1,20 @@
+package platform
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// FileTimes returns the last access, inode change and birth times
+// for a file, as far as they are available on the current
+// platform. Unavailable timestamps are returned as the zero time.
+func FileTimes(fi os.FileInfo) (atime, ctime, btime time.Time) {
+	d, ok := fi.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return
+	}
+	atime = time.Unix(0, d.LastAccessTime.Nanoseconds())
+	btime = time.Unix(0, d.CreationTime.Nanoseconds())
+	return
+}
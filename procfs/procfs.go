@@ -0,0 +1,370 @@
+// Package procfs reads process information from the Linux /proc
+// filesystem. The location of the filesystem can be changed, so that
+// scan modules can be tested against a fake procfs tree.
+package procfs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FS is the root directory of a procfs tree.
+type FS string
+
+// Default is the system's procfs.
+const Default FS = "/proc"
+
+// Pids returns the IDs of all processes, in ascending order.
+func (fs FS) Pids() ([]int, error) {
+	d, err := os.Open(string(fs))
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, name := range names {
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	return pids, nil
+}
+
+// BootID returns the random ID that the kernel generates at boot time,
+// without dashes.
+func (fs FS) BootID() (string, error) {
+	buf, err := ioutil.ReadFile(filepath.Join(string(fs), "sys", "kernel", "random", "boot_id"))
+	if err != nil {
+		return "", err
+	}
+	return strings.Replace(strings.TrimSpace(string(buf)), "-", "", -1), nil
+}
+
+// BootTime returns the time at which the system was booted, from the
+// btime field of /proc/stat.
+func (fs FS) BootTime() (time.Time, error) {
+	f, err := os.Open(filepath.Join(string(fs), "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[0] == "btime" {
+			sec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errors.New("no btime field in stat")
+}
+
+// ClockTicks is the unit of Stat.StartTime (USER_HZ), which is 100
+// on all common architectures.
+const ClockTicks = 100
+
+// StartTime converts Stat.StartTime to wall clock time.
+func StartTime(boot time.Time, ticks uint64) time.Time {
+	return boot.Add(time.Duration(ticks) * time.Second / ClockTicks)
+}
+
+// Key returns an identifier for a process that stays the same while
+// the process is running and is not reused for other processes, even
+// across reboots: the first 8 characters of the boot ID, the process
+// ID, and the start time.
+func Key(bootID string, pid int, startTime uint64) string {
+	if len(bootID) > 8 {
+		bootID = bootID[:8]
+	}
+	if bootID == "" {
+		return fmt.Sprintf("%d-%d", pid, startTime)
+	}
+	return fmt.Sprintf("%s-%d-%d", bootID, pid, startTime)
+}
+
+// Proc returns a handle for the process with the given ID. It is not
+// checked whether the process exists.
+func (fs FS) Proc(pid int) Proc { return Proc{fs: fs, PID: pid} }
+
+// Proc is a process in a procfs tree.
+type Proc struct {
+	fs  FS
+	PID int
+}
+
+// Path returns the path of a file in the process's procfs directory.
+func (p Proc) Path(elem ...string) string {
+	return filepath.Join(append([]string{string(p.fs), strconv.Itoa(p.PID)}, elem...)...)
+}
+
+func (p Proc) read(name string) ([]byte, error) { return ioutil.ReadFile(p.Path(name)) }
+
+func splitNul(buf []byte) []string {
+	buf = bytes.TrimRight(buf, "\x00")
+	if len(buf) == 0 {
+		return nil
+	}
+	return strings.Split(string(buf), "\x00")
+}
+
+// Comm returns the command name of the process.
+func (p Proc) Comm() (string, error) {
+	buf, err := p.read("comm")
+	return strings.TrimRight(string(buf), "\n"), err
+}
+
+// Cmdline returns the command line arguments of the process. It is
+// empty for kernel threads.
+func (p Proc) Cmdline() ([]string, error) {
+	buf, err := p.read("cmdline")
+	return splitNul(buf), err
+}
+
+// Environ returns the initial environment of the process.
+func (p Proc) Environ() ([]string, error) {
+	buf, err := p.read("environ")
+	return splitNul(buf), err
+}
+
+// Getenv returns the value of an environment variable from the
+// initial environment of the process.
+func Getenv(environ []string, name string) (string, bool) {
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, name+"=") {
+			return kv[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// Exe returns the target of the process's exe link. For executables
+// that have been removed, the kernel appends " (deleted)".
+func (p Proc) Exe() (string, error) { return os.Readlink(p.Path("exe")) }
+
+// Cwd returns the target of the process's cwd link.
+func (p Proc) Cwd() (string, error) { return os.Readlink(p.Path("cwd")) }
+
+// Key returns the process's identifier, see Key. It is empty if the
+// process does not exist.
+func (p Proc) Key() string {
+	st, err := p.Stat()
+	if err != nil {
+		return ""
+	}
+	return p.key(st)
+}
+
+func (p Proc) key(st Stat) string {
+	bootID, _ := p.fs.BootID()
+	return Key(bootID, p.PID, st.StartTime)
+}
+
+// Namespaces returns the namespaces of the process, e.g. "mnt" ->
+// "mnt:[4026531841]".
+func (p Proc) Namespaces() (map[string]string, error) {
+	d, err := os.Open(p.Path("ns"))
+	if err != nil {
+		return nil, err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return nil, err
+	}
+	ns := make(map[string]string)
+	for _, name := range names {
+		if target, err := os.Readlink(p.Path("ns", name)); err == nil {
+			ns[name] = target
+		}
+	}
+	return ns, nil
+}
+
+// Stat contains selected fields of /proc/<pid>/stat.
+type Stat struct {
+	Comm      string
+	State     string
+	PPID      int
+	Flags     uint64
+	StartTime uint64 // in clock ticks after system boot
+}
+
+// FlagKernelThread (PF_KTHREAD) is set in Stat.Flags for kernel
+// threads.
+const FlagKernelThread = 0x00200000
+
+// Stat reads /proc/<pid>/stat.
+func (p Proc) Stat() (Stat, error) {
+	buf, err := p.read("stat")
+	if err != nil {
+		return Stat{}, err
+	}
+	// The command name is enclosed in parentheses and may itself
+	// contain spaces and parentheses.
+	s := string(buf)
+	open, close := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || close < open {
+		return Stat{}, errors.New("malformed stat")
+	}
+	fields := strings.Fields(s[close+1:])
+	// fields[0] is field 3 (state) in proc(5).
+	if len(fields) < 20 {
+		return Stat{}, errors.New("malformed stat")
+	}
+	st := Stat{Comm: s[open+1 : close], State: fields[0]}
+	if st.PPID, err = strconv.Atoi(fields[1]); err != nil {
+		return Stat{}, err
+	}
+	if st.Flags, err = strconv.ParseUint(fields[6], 10, 64); err != nil {
+		return Stat{}, err
+	}
+	if st.StartTime, err = strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return Stat{}, err
+	}
+	return st, nil
+}
+
+// UID returns the real user ID of the process from
+// /proc/<pid>/status.
+func (p Proc) UID() (string, error) {
+	f, err := os.Open(p.Path("status"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 1 && fields[0] == "Uid:" {
+			return fields[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("no Uid field in status")
+}
+
+// Mapping is a memory mapping from /proc/<pid>/maps.
+type Mapping struct {
+	Start, End uint64
+	Perms      string // e.g. "r-xp"
+	Offset     uint64
+	Dev        string
+	Inode      uint64
+	Path       string // file name, pseudo-path such as [heap], or empty
+}
+
+// Anonymous reports whether the mapping is not backed by a file.
+func (m *Mapping) Anonymous() bool {
+	return m.Inode == 0 && (m.Path == "" || strings.HasPrefix(m.Path, "["))
+}
+
+// Deleted reports whether the file backing the mapping has been
+// removed.
+func (m *Mapping) Deleted() bool { return strings.HasSuffix(m.Path, " (deleted)") }
+
+// Maps reads /proc/<pid>/maps.
+func (p Proc) Maps() ([]Mapping, error) {
+	f, err := os.Open(p.Path("maps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var maps []Mapping
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m, err := parseMapping(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		maps = append(maps, m)
+	}
+	return maps, scanner.Err()
+}
+
+// parseMapping parses a line such as
+// "7f0c3a000000-7f0c3a021000 rw-p 00000000 00:00 0  [heap]".
+func parseMapping(line string) (m Mapping, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return m, errors.New("malformed maps line: " + line)
+	}
+	addr := strings.SplitN(fields[0], "-", 2)
+	if len(addr) != 2 {
+		return m, errors.New("malformed maps line: " + line)
+	}
+	if m.Start, err = strconv.ParseUint(addr[0], 16, 64); err != nil {
+		return
+	}
+	if m.End, err = strconv.ParseUint(addr[1], 16, 64); err != nil {
+		return
+	}
+	m.Perms = fields[1]
+	if m.Offset, err = strconv.ParseUint(fields[2], 16, 64); err != nil {
+		return
+	}
+	m.Dev = fields[3]
+	if m.Inode, err = strconv.ParseUint(fields[4], 10, 64); err != nil {
+		return
+	}
+	if len(fields) > 5 {
+		// The path may contain spaces; take everything after
+		// the inode field.
+		rest := line
+		for i := 0; i < 5; i++ {
+			rest = strings.TrimLeft(rest, " ")
+			rest = rest[strings.IndexByte(rest, ' '):]
+		}
+		m.Path = strings.TrimLeft(rest, " ")
+	}
+	return m, nil
+}
+
+// Info contains commonly reported properties of a process. Fields
+// that cannot be read, e.g. due to missing permissions or because the
+// process has exited, are left empty.
+type Info struct {
+	PID     int
+	Key     string // see Key
+	Stat    Stat
+	Comm    string
+	Exe     string
+	Cmdline []string
+	UID     string
+}
+
+// Info collects the properties of the process.
+func (p Proc) Info() Info {
+	info := Info{PID: p.PID}
+	var err error
+	if info.Stat, err = p.Stat(); err == nil {
+		info.Key = p.key(info.Stat)
+	}
+	info.Comm, _ = p.Comm()
+	if info.Comm == "" {
+		info.Comm = info.Stat.Comm
+	}
+	info.Exe, _ = p.Exe()
+	info.Cmdline, _ = p.Cmdline()
+	info.UID, _ = p.UID()
+	return info
+}
@@ -0,0 +1,107 @@
+package procfs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+func TestProc(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	tr.Mkdir("self", 0755)
+	files := map[string]string{
+		"comm":    "a) b\n",
+		"cmdline": "/bin/sh\x00-c\x00true\x00",
+		"environ": "HOME=/root\x00LD_PRELOAD=/tmp/x.so\x00",
+		"stat":    "42 (a) b) S 1 42 42 0 -1 4194560 100 0 0 0 1 2 0 0 20 0 1 0 12345 1000 100 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0\n",
+		"status":  "Name:\ta) b\nUid:\t1000\t1000\t1000\t1000\n",
+	}
+	for name, content := range files {
+		tr.Write("42/"+name, content)
+	}
+	tr.Symlink("/usr/bin/evil (deleted)", "42/exe")
+	tr.Symlink("mnt:[4026531841]", "42/ns/mnt")
+	tr.Symlink("net:[4026531840]", "42/ns/net")
+	tr.Write("sys/kernel/random/boot_id", "5ad2e5b4-3c1f-4d52-9a8e-0f5b1c2d3e4f\n")
+	tr.Write("stat", "cpu  1 2 3 4\nbtime 1600000000\nprocesses 42\n")
+
+	fs := FS(tr.Root)
+	if pids, err := fs.Pids(); err != nil || !reflect.DeepEqual(pids, []int{42}) {
+		t.Errorf("Pids: got %v, %v", pids, err)
+	}
+	p := fs.Proc(42)
+	if comm, _ := p.Comm(); comm != "a) b" {
+		t.Errorf("Comm: got %q", comm)
+	}
+	if args, _ := p.Cmdline(); !reflect.DeepEqual(args, []string{"/bin/sh", "-c", "true"}) {
+		t.Errorf("Cmdline: got %q", args)
+	}
+	env, _ := p.Environ()
+	if v, ok := Getenv(env, "LD_PRELOAD"); !ok || v != "/tmp/x.so" {
+		t.Errorf("Getenv: got %q, %v", v, ok)
+	}
+	if exe, _ := p.Exe(); exe != "/usr/bin/evil (deleted)" {
+		t.Errorf("Exe: got %q", exe)
+	}
+	st, err := p.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Stat{Comm: "a) b", State: "S", PPID: 1, Flags: 4194560, StartTime: 12345}); st != expected {
+		t.Errorf("Stat: got %+v, expected %+v", st, expected)
+	}
+	if uid, _ := p.UID(); uid != "1000" {
+		t.Errorf("UID: got %q", uid)
+	}
+	ns, err := p.Namespaces()
+	if expected := map[string]string{"mnt": "mnt:[4026531841]", "net": "net:[4026531840]"}; err != nil || !reflect.DeepEqual(ns, expected) {
+		t.Errorf("Namespaces: got %v, %v", ns, err)
+	}
+	boot, err := fs.BootTime()
+	if err != nil || boot.Unix() != 1600000000 {
+		t.Errorf("BootTime: got %v, %v", boot, err)
+	}
+	if start := StartTime(boot, st.StartTime); start.Unix() != 1600000123 {
+		t.Errorf("StartTime: got %v", start)
+	}
+	if info := p.Info(); info.Key != "5ad2e5b4-42-12345" {
+		t.Errorf("Info.Key: got %q", info.Key)
+	}
+	if key := fs.Proc(43).Key(); key != "" {
+		t.Errorf("Key of missing process: got %q", key)
+	}
+	if key := Key("", 42, 12345); key != "42-12345" {
+		t.Errorf("Key without boot ID: got %q", key)
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	for _, c := range []struct {
+		line     string
+		expected Mapping
+	}{
+		{"55d0c9a00000-55d0c9a21000 r-xp 00001000 fd:01 1234567                    /usr/bin/my prog (deleted)",
+			Mapping{0x55d0c9a00000, 0x55d0c9a21000, "r-xp", 0x1000, "fd:01", 1234567, "/usr/bin/my prog (deleted)"}},
+		{"7f0c3a000000-7f0c3a021000 rwxp 00000000 00:00 0 ",
+			Mapping{0x7f0c3a000000, 0x7f0c3a021000, "rwxp", 0, "00:00", 0, ""}},
+		{"7ffc1a2b3000-7ffc1a2d4000 rw-p 00000000 00:00 0                          [stack]",
+			Mapping{0x7ffc1a2b3000, 0x7ffc1a2d4000, "rw-p", 0, "00:00", 0, "[stack]"}},
+	} {
+		m, err := parseMapping(c.line)
+		if err != nil {
+			t.Errorf("%s: %v", c.line, err)
+		} else if m != c.expected {
+			t.Errorf("%s: got %+v, expected %+v", c.line, m, c.expected)
+		}
+	}
+	m, _ := parseMapping("7f0c3a000000-7f0c3a021000 rwxp 00000000 00:00 0")
+	if !m.Anonymous() {
+		t.Error("expected anonymous mapping")
+	}
+	m, _ = parseMapping("7f0c3a000000-7f0c3a021000 r-xp 00000000 00:05 42 /memfd:x (deleted)")
+	if m.Anonymous() || !m.Deleted() {
+		t.Error("expected deleted, file-backed mapping")
+	}
+}
@@ -0,0 +1,67 @@
+// Package procfstest builds fake file system trees, e.g. /proc, /sys
+// and /etc, that tests point scanners at instead of the real system.
+package procfstest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tree is a temporary directory that stands in for the root
+// directory. Names passed to its methods are relative to Root.
+type Tree struct {
+	Root string
+	t    testing.TB
+}
+
+// New creates an empty tree. Remove must be called when the test is
+// done.
+func New(t testing.TB) *Tree {
+	root, err := ioutil.TempDir("", "spyre-procfstest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Tree{Root: root, t: t}
+}
+
+// Remove deletes the tree.
+func (tr *Tree) Remove() { os.RemoveAll(tr.Root) }
+
+// Path returns the path of name within the tree.
+func (tr *Tree) Path(name string) string { return filepath.Join(tr.Root, name) }
+
+// Write creates a file and its parent directories.
+func (tr *Tree) Write(name, content string) {
+	p := tr.Path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		tr.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		tr.t.Fatal(err)
+	}
+}
+
+// Mkdir creates a directory and its parents.
+func (tr *Tree) Mkdir(name string, perm os.FileMode) {
+	if err := os.MkdirAll(tr.Path(name), perm); err != nil {
+		tr.t.Fatal(err)
+	}
+	// MkdirAll is subject to the umask.
+	if err := os.Chmod(tr.Path(name), perm); err != nil {
+		tr.t.Fatal(err)
+	}
+}
+
+// Symlink creates a symbolic link to target, which is used as is, and
+// the link's parent directories.
+func (tr *Tree) Symlink(target, name string) {
+	p := tr.Path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		tr.t.Fatal(err)
+	}
+	if err := os.Symlink(target, p); err != nil {
+		tr.t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package progress keeps track of the scan progress and estimates the
+// time remaining.
+package progress
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Status is a snapshot of the scan progress.
+type Status struct {
+	Phase                  string
+	Files, Bytes           int64
+	TotalFiles, TotalBytes int64
+	Current                string
+	Elapsed                time.Duration
+	// ETA is the estimated remaining time for the current phase;
+	// it is zero if no estimate is available.
+	ETA time.Duration
+}
+
+func (s Status) String() string {
+	str := fmt.Sprintf("phase=%s done=%d", s.Phase, s.Files)
+	if s.TotalFiles > 0 {
+		str += fmt.Sprintf("/%d", s.TotalFiles)
+	}
+	str += fmt.Sprintf(" bytes=%d", s.Bytes)
+	if s.TotalBytes > 0 {
+		str += fmt.Sprintf("/%d (%.1f%%)", s.TotalBytes, 100*float64(s.Bytes)/float64(s.TotalBytes))
+	}
+	str += fmt.Sprintf(" elapsed=%s", s.Elapsed.Round(time.Second))
+	if s.ETA > 0 {
+		str += fmt.Sprintf(" eta=%s", s.ETA.Round(time.Second))
+	}
+	if s.Current != "" {
+		str += " current=" + s.Current
+	}
+	return str
+}
+
+// Tracker is safe for concurrent use.
+type Tracker struct {
+	mu                     sync.Mutex
+	phase                  string
+	start, phaseStart      time.Time
+	files, bytes           int64
+	totalFiles, totalBytes int64
+	current                string
+}
+
+func NewTracker() *Tracker {
+	now := time.Now()
+	return &Tracker{start: now, phaseStart: now}
+}
+
+// SetPhase starts a new scan phase and resets counters and estimates.
+func (t *Tracker) SetPhase(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phase = name
+	t.phaseStart = time.Now()
+	t.files, t.bytes, t.totalFiles, t.totalBytes = 0, 0, 0, 0
+	t.current = ""
+}
+
+// SetEstimate sets the expected amount of work for the current phase.
+// Either value may be zero if unknown.
+func (t *Tracker) SetEstimate(files, bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.totalFiles, t.totalBytes = files, bytes
+}
+
+// Begin records the item that is currently being scanned.
+func (t *Tracker) Begin(current string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current = current
+}
+
+// Done records that an item of the given size has been processed.
+func (t *Tracker) Done(size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files++
+	t.bytes += size
+}
+
+func (t *Tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	s := Status{
+		Phase:      t.phase,
+		Files:      t.files,
+		Bytes:      t.bytes,
+		TotalFiles: t.totalFiles,
+		TotalBytes: t.totalBytes,
+		Current:    t.current,
+		Elapsed:    now.Sub(t.start),
+	}
+	s.ETA = eta(now.Sub(t.phaseStart), t.bytes, t.totalBytes)
+	if s.ETA == 0 {
+		s.ETA = eta(now.Sub(t.phaseStart), t.files, t.totalFiles)
+	}
+	return s
+}
+
+// eta extrapolates the remaining time from the elapsed time and the
+// fraction of work done.
+func eta(elapsed time.Duration, done, total int64) time.Duration {
+	if done <= 0 || total <= done {
+		return 0
+	}
+	return time.Duration(float64(elapsed) * float64(total-done) / float64(done))
+}
+
+// Run calls emit with the current status every interval until the
+// returned stop function is called. stop waits for a running emit
+// call to return.
+func (t *Tracker) Run(interval time.Duration, emit func(Status)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ticker.C:
+				emit(t.Status())
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		wg.Wait()
+	}
+}
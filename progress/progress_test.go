@@ -0,0 +1,63 @@
+package progress
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestETA(t *testing.T) {
+	for _, test := range []struct {
+		elapsed     time.Duration
+		done, total int64
+		expected    time.Duration
+	}{
+		{time.Minute, 25, 100, 3 * time.Minute},
+		{time.Minute, 0, 100, 0},
+		{time.Minute, 100, 100, 0},
+		// Estimate was too low
+		{time.Minute, 150, 100, 0},
+		{time.Minute, 10, 0, 0},
+	} {
+		if got := eta(test.elapsed, test.done, test.total); got != test.expected {
+			t.Errorf("eta(%v, %d, %d): got %v, expected %v",
+				test.elapsed, test.done, test.total, got, test.expected)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	tr.SetPhase("file")
+	tr.SetEstimate(4, 400)
+	tr.Begin("/etc/passwd")
+	tr.Done(100)
+	s := tr.Status()
+	if s.Phase != "file" || s.Files != 1 || s.Bytes != 100 || s.Current != "/etc/passwd" {
+		t.Errorf("unexpected status: %+v", s)
+	}
+	tr.SetPhase("evtx")
+	if s := tr.Status(); s.Files != 0 || s.TotalBytes != 0 || s.ETA != 0 {
+		t.Errorf("counters not reset: %+v", s)
+	}
+}
+
+func TestRunStop(t *testing.T) {
+	tr := NewTracker()
+	started := make(chan struct{}, 1)
+	var finished int32
+	stop := tr.Run(time.Millisecond, func(Status) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+	})
+	<-started
+	stop()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("stop returned while emit was running")
+	}
+	stop()
+}
@@ -0,0 +1,254 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/pathmatch"
+
+	"github.com/spf13/afero"
+
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// allowEntry describes findings that are known false positives. A
+// finding is suppressed if all conditions that are set in the entry
+// hold.
+type allowEntry struct {
+	line    int
+	rule    string // glob
+	path    *pathmatch.Matcher
+	sha256  string
+	process string // glob, case-insensitive
+	signer  string // glob, case-insensitive
+	channel string
+	eventID string
+}
+
+var allowlist []allowEntry
+
+// loadAllowlist reads the allowlist file from the configuration. A
+// missing file is not an error.
+func loadAllowlist() error {
+	allowlist = nil
+	if config.Allowlist == "" {
+		return nil
+	}
+	f, err := config.Fs.Open(config.Allowlist)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Debugf("No allowlist %s found", config.Allowlist)
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	if allowlist, err = parseAllowlist(f); err != nil {
+		return fmt.Errorf("allowlist %s: %v", config.Allowlist, err)
+	}
+	log.Infof("Loaded %d allowlist entries from %s", len(allowlist), config.Allowlist)
+	return nil
+}
+
+// parseAllowlist parses an allowlist. Each line contains one or more
+// key=value conditions, separated by ";". Empty lines and lines
+// starting with # are ignored.
+func parseAllowlist(r io.Reader) ([]allowEntry, error) {
+	var entries []allowEntry
+	scanner := bufio.NewScanner(r)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		e := allowEntry{line: lineno}
+		for _, cond := range strings.Split(line, ";") {
+			cond = strings.TrimSpace(cond)
+			if cond == "" {
+				continue
+			}
+			i := strings.Index(cond, "=")
+			if i < 0 {
+				return nil, fmt.Errorf("line %d: expected key=value, got '%s'", lineno, cond)
+			}
+			key, value := strings.TrimSpace(cond[:i]), strings.TrimSpace(cond[i+1:])
+			if value == "" {
+				return nil, fmt.Errorf("line %d: empty value for %s", lineno, key)
+			}
+			var err error
+			switch key {
+			case "rule":
+				e.rule, err = value, checkGlob(value)
+			case "path":
+				e.path = &pathmatch.Matcher{}
+				err = e.path.Add(value)
+			case "sha256":
+				if len(value) != 2*sha256.Size {
+					err = errors.New("invalid SHA-256 hash")
+				}
+				e.sha256 = strings.ToLower(value)
+			case "process":
+				e.process, err = strings.ToLower(value), checkGlob(value)
+			case "signer":
+				e.signer, err = strings.ToLower(value), checkGlob(value)
+			case "channel":
+				e.channel = value
+			case "event_id":
+				e.eventID = value
+			default:
+				err = errors.New("unknown key")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", lineno, key, err)
+			}
+		}
+		if e == (allowEntry{line: lineno}) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func checkGlob(pattern string) error {
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+func globMatch(pattern, s string) bool {
+	ok, _ := path.Match(pattern, s)
+	return ok
+}
+
+// allowRecord contains the fields of a finding that allowlist
+// entries are matched against.
+type allowRecord struct {
+	file      afero.File
+	rule      string
+	paths     []string
+	hashes    []string
+	processes []string
+	signer    string
+	channel   string
+	eventID   string
+}
+
+func newAllowRecord(file afero.File, extra []string) *allowRecord {
+	r := &allowRecord{file: file}
+	if file != nil {
+		r.paths = append(r.paths, file.Name())
+	}
+	for it := extra; len(it) >= 2; it = it[2:] {
+		k, v := it[0], it[1]
+		if v == "" {
+			continue
+		}
+		switch k {
+		case "rule":
+			r.rule = v
+		case "Filepath", "image_file":
+			r.paths = append(r.paths, v)
+		case "pathexe":
+			r.paths = append(r.paths, v)
+			r.processes = append(r.processes, strings.ToLower(filepath.Base(v)))
+		case "Filehash256", "new_sha256":
+			r.hashes = append(r.hashes, strings.ToLower(v))
+		case "Process":
+			r.processes = append(r.processes, strings.ToLower(v))
+		case "autorun_signed":
+			r.signer = strings.ToLower(v)
+		case "source_name":
+			r.channel = v
+		case "event_identifier":
+			r.eventID = v
+		}
+	}
+	return r
+}
+
+// fileHash computes the SHA-256 hash of the file the finding refers
+// to, if it is not already known.
+func (r *allowRecord) fileHash() {
+	if r.file == nil || len(r.hashes) > 0 {
+		return
+	}
+	pos, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	defer r.file.Seek(pos, io.SeekStart)
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r.file); err != nil {
+		log.Debugf("Could not hash %s for allowlist: %v", r.file.Name(), err)
+		return
+	}
+	r.hashes = append(r.hashes, hex.EncodeToString(h.Sum(nil)))
+}
+
+func (e *allowEntry) match(r *allowRecord) bool {
+	if e.rule != "" && !globMatch(e.rule, r.rule) {
+		return false
+	}
+	if e.channel != "" && !strings.EqualFold(e.channel, r.channel) {
+		return false
+	}
+	if e.eventID != "" && e.eventID != r.eventID {
+		return false
+	}
+	if e.signer != "" && !globMatch(e.signer, r.signer) {
+		return false
+	}
+	if e.process != "" && !anyMatch(r.processes, func(p string) bool { return globMatch(e.process, p) }) {
+		return false
+	}
+	if e.path != nil && !anyMatch(r.paths, func(p string) bool { return e.path.Match(p, false) }) {
+		return false
+	}
+	if e.sha256 != "" {
+		r.fileHash()
+		if !anyMatch(r.hashes, func(h string) bool { return h == e.sha256 }) {
+			return false
+		}
+	}
+	return true
+}
+
+func anyMatch(values []string, f func(string) bool) bool {
+	for _, v := range values {
+		if f(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// suppress reports whether a finding matches the allowlist. Records
+// that are not findings are never suppressed.
+func suppress(file afero.File, description, message string, extra []string) bool {
+	if len(allowlist) == 0 {
+		return false
+	}
+	if _, ok := findingRule(extra); !ok {
+		return false
+	}
+	r := newAllowRecord(file, extra)
+	for i := range allowlist {
+		if allowlist[i].match(r) {
+			suppressed++
+			log.Debugf("Suppressed %s (allowlist line %d): %s", description, allowlist[i].line, message)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"github.com/spf13/afero"
+
+	"strings"
+	"testing"
+)
+
+const testAllowlist = `
+# known false positives
+rule=Webshell_*; path=/var/www/**/cache/*.php
+sha256=E3B0C44298FC1C149AFBF4C8996FB92427AE41E4649B934CA495991B7852B855
+rule=Mimikatz; process=procdump*.exe
+signer=(Verified) Microsoft*
+channel=Microsoft-Windows-Sysmon/Operational; event_id=1
+`
+
+func TestAllowlist(t *testing.T) {
+	entries, err := parseAllowlist(strings.NewReader(testAllowlist))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("got %d entries, expected 5", len(entries))
+	}
+	allowlist = entries
+	suppressed = 0
+	defer func() { allowlist = nil; suppressed = 0 }()
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/tmp/empty", nil, 0644)
+	afero.WriteFile(fs, "/tmp/other", []byte("x"), 0644)
+	open := func(name string) afero.File {
+		f, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+
+	for _, c := range []struct {
+		file     afero.File
+		extra    []string
+		expected bool
+	}{
+		{nil, []string{"rule", "Webshell_PHP", "Filepath", "/var/www/site/a/cache/x.php"}, true},
+		{nil, []string{"rule", "Webshell_PHP", "Filepath", "/var/www/site/x.php"}, false},
+		{nil, []string{"rule", "Other", "Filepath", "/var/www/site/a/cache/x.php"}, false},
+		{open("/tmp/empty"), []string{"rule", "Any"}, true},
+		{open("/tmp/other"), []string{"rule", "Any"}, false},
+		{nil, []string{"rule", "Mimikatz", "Process", "PROCDUMP64.EXE"}, true},
+		{nil, []string{"rule", "Mimikatz", "pathexe", `/opt/procdump.exe`}, true},
+		{nil, []string{"rule", "Mimikatz", "Process", "lsass.exe"}, false},
+		{nil, []string{"rule", "autorun", "autorun_signed", "(Verified) Microsoft Windows"}, true},
+		{nil, []string{"rule", "r", "source_name", "Microsoft-Windows-Sysmon/Operational", "event_identifier", "1"}, true},
+		{nil, []string{"rule", "r", "source_name", "Microsoft-Windows-Sysmon/Operational", "event_identifier", "3"}, false},
+		{nil, []string{"rule", "Webshell_PHP", "Filepath", "/var/www/site/a/cache/x.php", "error", "oops"}, false},
+	} {
+		if got := suppress(c.file, "test", "test", c.extra); got != c.expected {
+			t.Errorf("%v: got %v, expected %v", c.extra, got, c.expected)
+		}
+	}
+	if suppressed != 6 {
+		t.Errorf("suppressed = %d, expected 6", suppressed)
+	}
+}
+
+func TestAllowlistInvalid(t *testing.T) {
+	for _, line := range []string{
+		"rule",
+		"rule=",
+		"color=blue",
+		"sha256=abcd",
+		"rule=[",
+	} {
+		if _, err := parseAllowlist(strings.NewReader(line)); err == nil {
+			t.Errorf("'%s': expected error", line)
+		}
+	}
+}
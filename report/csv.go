@@ -0,0 +1,116 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre"
+
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// csvColumns is the stable column set written by formatterCSV. Fields
+// that do not map to one of the named columns end up in "details".
+var csvColumns = []string{
+	"datetime", "hostname", "type", "rule", "message",
+	"path", "pid", "process", "hash", "details",
+}
+
+// csvPathKeys lists the fields that scan modules use to designate a
+// file system path, in order of preference.
+var csvPathKeys = []string{"Filepath", "pathexe", "image_file", "key_path"}
+
+type formatterCSV struct {
+	w *csv.Writer
+}
+
+func (f *formatterCSV) emitRow(w io.Writer, description, message, path string, extra ...string) {
+	if f.w == nil {
+		f.w = csv.NewWriter(w)
+		f.w.Write(csvColumns)
+	}
+	var rule, pid, process, hash string
+	var details []string
+	if len(extra)%2 != 0 {
+		extra = append(extra, "")
+	}
+	for it := extra; len(it) >= 2; it = it[2:] {
+		k, v := it[0], it[1]
+		switch {
+		case k == "rule":
+			rule = v
+		case k == "PID":
+			pid = v
+		case k == "Process":
+			process = v
+		case k == "Filehash":
+			hash = v
+		case path == "" && stringInSlice(k, csvPathKeys):
+			path = v
+		case k == "extracted_file" || k == "extracted_stdout" || k == "extracted_stderr":
+			// Base64-encoded blobs are not useful in a spreadsheet.
+			details = append(details, k+"=<"+fmt.Sprint(len(v))+" bytes>")
+		default:
+			details = append(details, k+"="+v)
+		}
+	}
+	f.w.Write([]string{
+		time.Now().Format(time.RFC3339), spyre.Hostname, description, rule, message,
+		path, pid, process, hash, strings.Join(details, "; "),
+	})
+	f.w.Flush()
+}
+
+func (f *formatterCSV) formatFileEntry(w io.Writer, file afero.File, description, message string, extra ...string) {
+	f.emitRow(w, description, message, file.Name(), extra...)
+}
+
+func (f *formatterCSV) formatEvtxEntry(w io.Writer, evt string, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatNetstatEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatAutorunEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatRegistryEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatProcEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitRow(w, description, message, "", extra...)
+}
+
+func (f *formatterCSV) formatMessage(w io.Writer, format string, a ...interface{}) {
+	f.emitRow(w, "msg", strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"), "")
+}
+
+func (f *formatterCSV) finish(w io.Writer) {
+	if f.w == nil {
+		// Emit at least the header so that consumers see a valid,
+		// empty table.
+		f.w = csv.NewWriter(w)
+		f.w.Write(csvColumns)
+	}
+	f.w.Flush()
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
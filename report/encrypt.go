@@ -0,0 +1,216 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre/config"
+
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Encrypted report streams consist of a header (magic, ephemeral
+// X25519 public key) followed by a sequence of chunks, each encrypted
+// using ChaCha20-Poly1305 with a key derived from the X25519 shared
+// secret. Every chunk is prefixed with its length. The chunk nonce
+// consists of a chunk counter and a flag that marks the final chunk,
+// so that reordering, removal and truncation of chunks are detected
+// (STREAM construction). Since report files are opened in append
+// mode, several streams may follow each other in a single file.
+const (
+	encMagic     = "SPYREENC1\n"
+	encChunkSize = 64 * 1024
+)
+
+// Fixed DER prefixes for X25519 keys as produced by `openssl genpkey
+// -algorithm x25519` (PKCS#8) and `openssl pkey -pubout` (PKIX).
+var (
+	x25519PKCS8Prefix = []byte{0x30, 0x2e, 0x02, 0x01, 0x00, 0x30, 0x05, 0x06, 0x03, 0x2b, 0x65, 0x6e, 0x04, 0x22, 0x04, 0x20}
+	x25519PKIXPrefix  = []byte{0x30, 0x2a, 0x30, 0x05, 0x06, 0x03, 0x2b, 0x65, 0x6e, 0x03, 0x21, 0x00}
+)
+
+// ParseX25519Key parses an X25519 public or private key, either
+// PEM-encoded or as 32 raw bytes, hex, or base64.
+func ParseX25519Key(buf []byte) ([]byte, error) {
+	if b, _ := pem.Decode(buf); b != nil {
+		for _, prefix := range [][]byte{x25519PKCS8Prefix, x25519PKIXPrefix} {
+			if len(b.Bytes) == len(prefix)+curve25519.ScalarSize && bytes.HasPrefix(b.Bytes, prefix) {
+				return b.Bytes[len(prefix):], nil
+			}
+		}
+		return nil, errors.New("not an X25519 key")
+	}
+	if k := decodeKey(buf); len(k) == curve25519.ScalarSize {
+		return k, nil
+	}
+	return nil, errors.New("could not parse X25519 key")
+}
+
+func streamAEAD(shared, ephemeral, recipient []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte{}, ephemeral...), recipient...)
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte("spyre report encryption")), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptWriter encrypts everything written to it to a X25519 public
+// key. Plaintext is buffered until a full chunk is available or the
+// writer is closed.
+type encryptWriter struct {
+	w         io.WriteCloser
+	recipient []byte
+	aead      cipher.AEAD
+	counter   uint64
+	buf       []byte
+}
+
+func (ew *encryptWriter) writeHeader() error {
+	scalar := make([]byte, curve25519.ScalarSize)
+	if _, err := rand.Read(scalar); err != nil {
+		return err
+	}
+	ephemeral, err := curve25519.X25519(scalar, curve25519.Basepoint)
+	if err != nil {
+		return err
+	}
+	shared, err := curve25519.X25519(scalar, ew.recipient)
+	if err != nil {
+		return err
+	}
+	if ew.aead, err = streamAEAD(shared, ephemeral, ew.recipient); err != nil {
+		return err
+	}
+	_, err = ew.w.Write(append([]byte(encMagic), ephemeral...))
+	return err
+}
+
+func (ew *encryptWriter) writeChunk(plain []byte, last bool) error {
+	if ew.aead == nil {
+		if err := ew.writeHeader(); err != nil {
+			return err
+		}
+	}
+	ct := ew.aead.Seal(make([]byte, 4, 4+len(plain)+ew.aead.Overhead()),
+		chunkNonce(ew.counter, last), plain, nil)
+	binary.BigEndian.PutUint32(ct[:4], uint32(len(ct)-4))
+	ew.counter++
+	_, err := ew.w.Write(ct)
+	return err
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	ew.buf = append(ew.buf, p...)
+	for len(ew.buf) > encChunkSize {
+		if err := ew.writeChunk(ew.buf[:encChunkSize], false); err != nil {
+			return 0, err
+		}
+		ew.buf = ew.buf[encChunkSize:]
+	}
+	return len(p), nil
+}
+
+func (ew *encryptWriter) Close() (err error) {
+	if ew.aead != nil || len(ew.buf) > 0 {
+		err = ew.writeChunk(ew.buf, true)
+		ew.buf = nil
+	}
+	if e := ew.w.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// readEncryptionKey reads the recipient's X25519 public key from the
+// configuration filesystem.
+func readEncryptionKey(file string) ([]byte, error) {
+	buf, err := afero.ReadFile(config.Fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("read encryption key: %v", err)
+	}
+	k, err := ParseX25519Key(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return k, nil
+}
+
+// Decrypt decrypts one or more consecutive encrypted report streams
+// from src using the recipient's X25519 private key and writes the
+// plaintext to dst.
+func Decrypt(dst io.Writer, src io.Reader, privateKey []byte) error {
+	public, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(src)
+	for streams := 0; ; streams++ {
+		hdr := make([]byte, len(encMagic)+curve25519.PointSize)
+		if _, err := io.ReadFull(r, hdr); err == io.EOF && streams > 0 {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("stream %d: read header: %v", streams+1, err)
+		}
+		if string(hdr[:len(encMagic)]) != encMagic {
+			return fmt.Errorf("stream %d: not an encrypted Spyre report", streams+1)
+		}
+		ephemeral := hdr[len(encMagic):]
+		shared, err := curve25519.X25519(privateKey, ephemeral)
+		if err != nil {
+			return err
+		}
+		aead, err := streamAEAD(shared, ephemeral, public)
+		if err != nil {
+			return err
+		}
+		for counter := uint64(0); ; counter++ {
+			var l [4]byte
+			if _, err := io.ReadFull(r, l[:]); err != nil {
+				return fmt.Errorf("stream %d: truncated after chunk %d", streams+1, counter)
+			}
+			n := binary.BigEndian.Uint32(l[:])
+			if n > encChunkSize+uint32(aead.Overhead()) {
+				return fmt.Errorf("stream %d: chunk %d: invalid length", streams+1, counter)
+			}
+			ct := make([]byte, n)
+			if _, err := io.ReadFull(r, ct); err != nil {
+				return fmt.Errorf("stream %d: truncated in chunk %d", streams+1, counter)
+			}
+			var last bool
+			plain, err := aead.Open(nil, chunkNonce(counter, false), ct, nil)
+			if err != nil {
+				if plain, err = aead.Open(nil, chunkNonce(counter, true), ct, nil); err != nil {
+					return fmt.Errorf("stream %d: chunk %d: decryption failed (wrong key or modified data)", streams+1, counter)
+				}
+				last = true
+			}
+			if _, err := dst.Write(plain); err != nil {
+				return err
+			}
+			if last {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	priv := bytes.Repeat([]byte{7}, curve25519.ScalarSize)
+	pub, _ := curve25519.X25519(priv, curve25519.Basepoint)
+
+	plain := strings.Repeat("some finding\n", 12345)
+	var buf bufferCloser
+	for i := 0; i < 2; i++ {
+		ew := &encryptWriter{w: &buf, recipient: pub}
+		ew.Write([]byte(plain))
+		ew.Close()
+	}
+	if bytes.Contains(buf.Bytes(), []byte("some finding")) {
+		t.Fatalf("ciphertext contains plaintext")
+	}
+
+	var out bytes.Buffer
+	if err := Decrypt(&out, bytes.NewReader(buf.Bytes()), priv); err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if out.String() != plain+plain {
+		t.Errorf("decrypted output does not match input")
+	}
+
+	stream := buf.Bytes()[:buf.Len()/2]
+	if err := Decrypt(&out, bytes.NewReader(stream[:len(stream)-100]), priv); err == nil {
+		t.Errorf("truncated stream was not detected")
+	}
+	if err := Decrypt(&out, bytes.NewReader(stream), bytes.Repeat([]byte{8}, curve25519.ScalarSize)); err == nil {
+		t.Errorf("decryption with wrong key did not fail")
+	}
+}
+
+type failingWriter struct{ closed bool }
+
+func (w *failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+func (w *failingWriter) Close() error              { w.closed = true; return nil }
+
+func TestEncryptCloseError(t *testing.T) {
+	priv := bytes.Repeat([]byte{7}, curve25519.ScalarSize)
+	pub, _ := curve25519.X25519(priv, curve25519.Basepoint)
+	var w failingWriter
+	ew := &encryptWriter{w: &w, recipient: pub}
+	ew.Write([]byte("some finding\n"))
+	if err := ew.Close(); err == nil {
+		t.Error("failed final chunk was not reported")
+	}
+	if !w.closed {
+		t.Error("underlying writer was not closed")
+	}
+}
@@ -63,6 +63,10 @@ func (f *formatterPlain) formatAutorunEntry(w io.Writer, description, message st
 	//w.Write([]byte{'\n'})
 }
 
+func (f *formatterPlain) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	fmt.Fprintf(w, "%s %s %s: %s%s\n", time.Now().Format(time.RFC3339), spyre.Hostname, description, message, fmtExtra(extra))
+}
+
 func (f *formatterPlain) formatEvtxEntry(w io.Writer, evt string, description, message string, extra ...string) {
 	// send directly all for avoid anomalie formated line
 	//f.emitTimeStamp(w)
@@ -140,6 +144,11 @@ func (f *formatterTSJSON) formatRegistryEntry(w io.Writer, description, message
 	f.emitRecord(w, extra...)
 }
 
+func (f *formatterTSJSON) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	extra = append([]string{"timestamp_desc", description, "message", message}, extra...)
+	f.emitRecord(w, extra...)
+}
+
 func (f *formatterTSJSON) formatProcEntry(w io.Writer, description, message string, extra ...string) {
 	extra = append([]string{"timestamp_desc", description, "message", message}, extra...)
 	f.emitRecord(w, extra...)
@@ -204,6 +213,11 @@ func (f *formatterTSJSONLines) formatRegistryEntry(w io.Writer, description, mes
 	f.emitRecord(w, extra...)
 }
 
+func (f *formatterTSJSONLines) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	extra = append([]string{"timestamp_desc", description, "message", message}, extra...)
+	f.emitRecord(w, extra...)
+}
+
 func (f *formatterTSJSONLines) formatProcEntry(w io.Writer, description, message string, extra ...string) {
 	extra = append([]string{"timestamp_desc", description, "message", message}, extra...)
 	f.emitRecord(w, extra...)
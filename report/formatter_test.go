@@ -0,0 +1,83 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatterCSV(t *testing.T) {
+	var buf bytes.Buffer
+	f := &formatterCSV{}
+	f.formatMessage(&buf, "Scan started at %s", "now")
+	f.formatProcEntry(&buf, "yara_on_pid", "matched",
+		"rule", "evil", "PID", "42", "Process", "bash", "Filehash", "d41d8cd9", "pathexe", "/bin/bash", "username", "root")
+	f.finish(&buf)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(csvColumns, ",") {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+	for i, expected := range map[int]string{2: "yara_on_pid", 3: "evil", 5: "/bin/bash", 6: "42", 7: "bash", 8: "d41d8cd9", 9: "username=root"} {
+		if got := records[2][i]; got != expected {
+			t.Errorf("column %s: expected %q, got %q", csvColumns[i], expected, got)
+		}
+	}
+}
+
+func TestFormatterHTML(t *testing.T) {
+	var buf bytes.Buffer
+	f := &formatterHTML{}
+	f.formatProcEntry(&buf, "yara_on_pid", "<script>", "rule", "evil", "PID", "42")
+	f.formatProcEntry(&buf, "yara_on_pid", "failed", "error", "access denied")
+	if buf.Len() != 0 {
+		t.Errorf("HTML formatter wrote output before finish")
+	}
+	f.finish(&buf)
+	out := buf.String()
+	for _, s := range []string{"<h3>yara_on_pid (1)</h3>", "<h4>evil (1)</h4>", "&lt;script&gt;", "error=access denied"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("output does not contain %q", s)
+		}
+	}
+}
+
+func TestFormatterTimeline(t *testing.T) {
+	var buf bytes.Buffer
+	f := &formatterTimeline{}
+	f.formatProcEntry(&buf, "yara_on_file", "matched", "rule", "evil",
+		"time_modified", "2021-03-03T10:00:00Z", "time_accessed", "", "time_changed", "2021-03-04T11:00:00.5Z")
+	f.formatProcEntry(&buf, "connect", "open", "rule", "x")
+	dec := json.NewDecoder(&buf)
+	var records []map[string]string
+	for dec.More() {
+		var r map[string]string
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	for i, expected := range []struct{ desc, ts string }{
+		{"Content Modification Time", "1614765600000000"},
+		{"Metadata Modification Time", "1614855600500000"},
+		{"Spyre Scan Time", ""},
+	} {
+		if got := records[i]["timestamp_desc"]; got != expected.desc {
+			t.Errorf("record %d: expected timestamp_desc %q, got %q", i, expected.desc, got)
+		}
+		if expected.ts != "" && records[i]["timestamp"] != expected.ts {
+			t.Errorf("record %d: expected timestamp %s, got %s", i, expected.ts, records[i]["timestamp"])
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre"
+
+	"fmt"
+	"html/template"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+type htmlField struct {
+	Key, Value string
+}
+
+type htmlEntry struct {
+	Time    time.Time
+	Message string
+	Path    string
+	Fields  []htmlField
+}
+
+type htmlRule struct {
+	Name    string
+	Entries []htmlEntry
+}
+
+type htmlModule struct {
+	Name  string
+	Count int
+	Rules []*htmlRule
+}
+
+// formatterHTML collects all findings in memory and renders a
+// self-contained summary page when the report is closed.
+type formatterHTML struct {
+	start, end time.Time
+	modules    []*htmlModule
+	messages   []htmlEntry
+	errors     []htmlEntry
+	info       []htmlEntry
+}
+
+func (f *formatterHTML) touch() time.Time {
+	now := time.Now()
+	if f.start.IsZero() {
+		f.start = now
+	}
+	f.end = now
+	return now
+}
+
+func (f *formatterHTML) addEntry(description, message, path string, extra ...string) {
+	e := htmlEntry{Time: f.touch(), Message: message, Path: path}
+	var rule string
+	var isError bool
+	if len(extra)%2 != 0 {
+		extra = append(extra, "")
+	}
+	for it := extra; len(it) >= 2; it = it[2:] {
+		k, v := it[0], it[1]
+		switch k {
+		case "rule":
+			rule = v
+			continue
+		case "error":
+			isError = true
+		case "extracted_file", "extracted_stdout", "extracted_stderr":
+			v = fmt.Sprintf("<%d bytes, base64-encoded>", len(v))
+		}
+		if v != "" {
+			e.Fields = append(e.Fields, htmlField{k, v})
+		}
+	}
+	if isError {
+		f.errors = append(f.errors, e)
+		return
+	}
+	if rule == "" {
+		rule = "(no rule)"
+	}
+	var m *htmlModule
+	for _, mm := range f.modules {
+		if mm.Name == description {
+			m = mm
+			break
+		}
+	}
+	if m == nil {
+		m = &htmlModule{Name: description}
+		f.modules = append(f.modules, m)
+	}
+	m.Count++
+	var r *htmlRule
+	for _, rr := range m.Rules {
+		if rr.Name == rule {
+			r = rr
+			break
+		}
+	}
+	if r == nil {
+		r = &htmlRule{Name: rule}
+		m.Rules = append(m.Rules, r)
+	}
+	r.Entries = append(r.Entries, e)
+}
+
+func (f *formatterHTML) formatFileEntry(w io.Writer, file afero.File, description, message string, extra ...string) {
+	f.addEntry(description, message, file.Name(), extra...)
+}
+
+func (f *formatterHTML) formatEvtxEntry(w io.Writer, evt string, description, message string, extra ...string) {
+	f.addEntry(description, message, "", extra...)
+}
+
+func (f *formatterHTML) formatNetstatEntry(w io.Writer, description, message string, extra ...string) {
+	f.addEntry(description, message, "", extra...)
+}
+
+func (f *formatterHTML) formatAutorunEntry(w io.Writer, description, message string, extra ...string) {
+	f.addEntry(description, message, "", extra...)
+}
+
+func (f *formatterHTML) formatRegistryEntry(w io.Writer, description, message string, extra ...string) {
+	f.addEntry(description, message, "", extra...)
+}
+
+// formatSystemEntry treats records that name a rule as findings;
+// others (e.g. the scan summary) are listed as scan information.
+func (f *formatterHTML) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	for it := extra; len(it) >= 2; it = it[2:] {
+		if it[0] == "rule" && it[1] != "" {
+			f.addEntry(description, message, "", extra...)
+			return
+		}
+	}
+	e := htmlEntry{Time: f.touch(), Message: message}
+	for it := extra; len(it) >= 2; it = it[2:] {
+		e.Fields = append(e.Fields, htmlField{it[0], it[1]})
+	}
+	f.info = append(f.info, e)
+}
+
+func (f *formatterHTML) formatProcEntry(w io.Writer, description, message string, extra ...string) {
+	f.addEntry(description, message, "", extra...)
+}
+
+func (f *formatterHTML) formatMessage(w io.Writer, format string, a ...interface{}) {
+	f.messages = append(f.messages, htmlEntry{
+		Time:    f.touch(),
+		Message: strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"),
+	})
+}
+
+func (f *formatterHTML) finish(w io.Writer) {
+	if f.start.IsZero() {
+		f.touch()
+	}
+	sort.SliceStable(f.modules, func(i, j int) bool { return f.modules[i].Name < f.modules[j].Name })
+	for _, m := range f.modules {
+		sort.SliceStable(m.Rules, func(i, j int) bool { return m.Rules[i].Name < m.Rules[j].Name })
+	}
+	var total int
+	for _, m := range f.modules {
+		total += m.Count
+	}
+	data := struct {
+		Hostname, Version, Platform string
+		Start, End                  time.Time
+		Duration                    time.Duration
+		Total                       int
+		Modules                     []*htmlModule
+		Messages, Errors, Info      []htmlEntry
+	}{
+		Hostname: spyre.Hostname,
+		Version:  spyre.Version,
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+		Start:    f.start,
+		End:      f.end,
+		Duration: f.end.Sub(f.start).Round(time.Second),
+		Total:    total,
+		Modules:  f.modules,
+		Messages: f.messages,
+		Errors:   f.errors,
+		Info:     f.info,
+	}
+	if err := htmlTemplate.Execute(w, data); err != nil {
+		fmt.Fprintf(w, "<!-- template error: %s -->\n", template.HTMLEscapeString(err.Error()))
+	}
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ts": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Spyre report: {{.Hostname}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+th { background: #eee; }
+td.fields { font-family: monospace; font-size: 90%; white-space: pre-wrap; word-break: break-all; }
+h2 { border-bottom: 2px solid #444; }
+</style>
+</head>
+<body>
+<h1>Spyre report: {{.Hostname}}</h1>
+<h2>Host</h2>
+<table>
+<tr><th>Hostname</th><td>{{.Hostname}}</td></tr>
+<tr><th>Spyre version</th><td>{{.Version}}</td></tr>
+<tr><th>Platform</th><td>{{.Platform}}</td></tr>
+<tr><th>First record</th><td>{{ts .Start}}</td></tr>
+<tr><th>Last record</th><td>{{ts .End}}</td></tr>
+<tr><th>Scan duration</th><td>{{.Duration}}</td></tr>
+<tr><th>Findings</th><td>{{.Total}}</td></tr>
+<tr><th>Errors</th><td>{{len .Errors}}</td></tr>
+</table>
+<h2>Findings</h2>
+{{if not .Modules}}<p>No findings.</p>{{end}}
+{{range .Modules}}
+<h3>{{.Name}} ({{.Count}})</h3>
+{{range .Rules}}
+<h4>{{.Name}} ({{len .Entries}})</h4>
+<table>
+<tr><th>Time</th><th>Message</th><th>Path</th><th>Details</th></tr>
+{{range .Entries}}<tr><td>{{ts .Time}}</td><td>{{.Message}}</td><td>{{.Path}}</td><td class="fields">{{range .Fields}}{{.Key}}={{.Value}}
+{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{end}}
+<h2>Errors</h2>
+{{if not .Errors}}<p>No errors.</p>{{else}}
+<table>
+<tr><th>Time</th><th>Message</th><th>Path</th><th>Details</th></tr>
+{{range .Errors}}<tr><td>{{ts .Time}}</td><td>{{.Message}}</td><td>{{.Path}}</td><td class="fields">{{range .Fields}}{{.Key}}={{.Value}}
+{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{if .Info}}<h2>Scan information</h2>
+<table>
+<tr><th>Time</th><th>Message</th><th>Details</th></tr>
+{{range .Info}}<tr><td>{{ts .Time}}</td><td>{{.Message}}</td><td class="fields">{{range .Fields}}{{.Key}}={{.Value}}
+{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Messages</h2>
+<table>
+{{range .Messages}}<tr><td>{{ts .Time}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,375 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// chainWriter implements hash chaining for line-based JSON report
+// formats. Every record is amended with a "chain" field containing
+// SHA256(previous chain value || record), where record is the JSON
+// object as written by the formatter, before the chain field has
+// been added. When the writer is closed, a trailer record containing
+// the record count and the final chain value is appended; if a
+// signing key has been configured, the trailer is signed.
+//
+// Each scan run appended to a report forms a segment. A segment's
+// chain starts from the chain value of the previous segment's
+// trailer, which is kept in the state file; the first record and the
+// trailer carry that value in a "chain_prev" field.
+type chainWriter struct {
+	w       io.WriteCloser
+	key     ed25519.PrivateKey
+	state   string
+	started bool
+	prev    string
+	chain   [sha256.Size]byte
+	records int
+	pending []byte
+}
+
+const (
+	chainFieldLen     = len(`,"chain":""`) + 2*sha256.Size
+	chainPrevFieldLen = len(`,"chain_prev":""`) + 2*sha256.Size
+)
+
+// start links the segment to the previous one, if any.
+func (cw *chainWriter) start() {
+	if cw.started {
+		return
+	}
+	cw.started = true
+	if cw.state == "" {
+		return
+	}
+	buf, err := ioutil.ReadFile(cw.state)
+	if err != nil {
+		return
+	}
+	prev, err := hex.DecodeString(strings.TrimSpace(string(buf)))
+	if err != nil || len(prev) != sha256.Size {
+		log.Errorf("Invalid chain state in %s, not linking report segment", cw.state)
+		return
+	}
+	copy(cw.chain[:], prev)
+	cw.prev = hex.EncodeToString(prev)
+}
+
+func (cw *chainWriter) Write(buf []byte) (int, error) {
+	cw.pending = append(cw.pending, buf...)
+	for {
+		i := bytes.IndexByte(cw.pending, '\n')
+		if i < 0 {
+			break
+		}
+		if err := cw.writeRecord(cw.pending[:i]); err != nil {
+			return 0, err
+		}
+		cw.pending = cw.pending[i+1:]
+	}
+	return len(buf), nil
+}
+
+func (cw *chainWriter) writeRecord(rec []byte) error {
+	if len(rec) == 0 || rec[len(rec)-1] != '}' {
+		return errors.New("chain: record is not a JSON object")
+	}
+	cw.start()
+	first := cw.records == 0
+	h := sha256.New()
+	h.Write(cw.chain[:])
+	h.Write(rec)
+	copy(cw.chain[:], h.Sum(nil))
+	cw.records++
+	out := make([]byte, 0, len(rec)+chainPrevFieldLen+chainFieldLen+1)
+	out = append(out, rec[:len(rec)-1]...)
+	if first && cw.prev != "" {
+		out = append(out, `,"chain_prev":"`...)
+		out = append(out, cw.prev...)
+		out = append(out, '"')
+	}
+	out = append(out, `,"chain":"`...)
+	out = append(out, hex.EncodeToString(cw.chain[:])...)
+	out = append(out, "\"}\n"...)
+	_, err := cw.w.Write(out)
+	return err
+}
+
+// trailerRecord is the last record written by chainWriter.
+type trailerRecord struct {
+	RecordType string `json:"record_type"`
+	Records    string `json:"records"`
+	Prev       string `json:"chain_prev,omitempty"`
+	Chain      string `json:"chain"`
+	Signature  string `json:"signature,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+	Hostname   string `json:"computer_name"`
+	Datetime   string `json:"datetime"`
+}
+
+// trailerMessage returns the data that is signed in the trailer.
+func trailerMessage(t trailerRecord) []byte {
+	msg := "spyre-report-trailer " + t.Chain + " " + t.Records
+	if t.Prev != "" {
+		msg += " " + t.Prev
+	}
+	return []byte(msg)
+}
+
+func (cw *chainWriter) Close() error {
+	if len(cw.pending) > 0 {
+		cw.writeRecord(cw.pending)
+		cw.pending = nil
+	}
+	cw.start()
+	t := trailerRecord{
+		RecordType: "trailer",
+		Records:    strconv.Itoa(cw.records),
+		Prev:       cw.prev,
+		Chain:      hex.EncodeToString(cw.chain[:]),
+		Hostname:   spyre.Hostname,
+		Datetime:   time.Now().Format(time.RFC3339),
+	}
+	if cw.key != nil {
+		t.Signature = base64.StdEncoding.EncodeToString(
+			ed25519.Sign(cw.key, trailerMessage(t)))
+		t.PublicKey = base64.StdEncoding.EncodeToString(cw.key.Public().(ed25519.PublicKey))
+	}
+	buf, _ := json.Marshal(t)
+	_, err := cw.w.Write(append(buf, '\n'))
+	if e := cw.w.Close(); err == nil {
+		err = e
+	}
+	if err == nil && cw.state != "" {
+		if err := ioutil.WriteFile(cw.state, []byte(t.Chain+"\n"), 0666); err != nil {
+			log.Errorf("Could not write chain state to %s: %v", cw.state, err)
+		}
+	}
+	return err
+}
+
+// decodeKey decodes key material that has been stored as hex or
+// base64 text or as raw bytes.
+func decodeKey(buf []byte) []byte {
+	text := bytes.TrimSpace(buf)
+	if k, err := hex.DecodeString(string(text)); err == nil {
+		return k
+	}
+	if k, err := base64.StdEncoding.DecodeString(string(text)); err == nil {
+		return k
+	}
+	return buf
+}
+
+// ParsePrivateKey parses an Ed25519 private key. Accepted formats
+// are PEM-encoded PKCS#8 (as produced by `openssl genpkey -algorithm
+// ed25519`) and the 32-byte seed or 64-byte private key as raw
+// bytes, hex, or base64.
+func ParsePrivateKey(buf []byte) (ed25519.PrivateKey, error) {
+	if b, _ := pem.Decode(buf); b != nil {
+		k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if pk, ok := k.(ed25519.PrivateKey); ok {
+			return pk, nil
+		}
+		return nil, errors.New("not an Ed25519 private key")
+	}
+	switch k := decodeKey(buf); len(k) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(k), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(k), nil
+	}
+	return nil, errors.New("could not parse Ed25519 private key")
+}
+
+// ParsePublicKey parses an Ed25519 public key, either PEM-encoded
+// (PKIX) or as 32 raw bytes, hex, or base64.
+func ParsePublicKey(buf []byte) (ed25519.PublicKey, error) {
+	if b, _ := pem.Decode(buf); b != nil {
+		k, err := x509.ParsePKIXPublicKey(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		if pk, ok := k.(ed25519.PublicKey); ok {
+			return pk, nil
+		}
+		return nil, errors.New("not an Ed25519 public key")
+	}
+	if k := decodeKey(buf); len(k) == ed25519.PublicKeySize {
+		return ed25519.PublicKey(k), nil
+	}
+	return nil, errors.New("could not parse Ed25519 public key")
+}
+
+// readSigningKey reads an Ed25519 private key from the configuration
+// filesystem.
+func readSigningKey(file string) (ed25519.PrivateKey, error) {
+	buf, err := afero.ReadFile(config.Fs, file)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %v", err)
+	}
+	k, err := ParsePrivateKey(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return k, nil
+}
+
+// VerifyResult describes a report stream that has been checked using
+// Verify.
+type VerifyResult struct {
+	// Segments is the number of complete chains, i.e. scan runs
+	// that have been appended to the same report.
+	Segments int
+	// Records is the total number of chained records.
+	Records int
+	// Signed is the number of segments with a valid signature.
+	Signed int
+	// Prev is the chain value that the first segment is linked to,
+	// i.e. the last trailer of an earlier report file (e.g. one that
+	// has been rotated away), if any.
+	Prev string
+	// Chain is the chain value of the last trailer. Segments that
+	// have been removed from the end of a report can only be
+	// detected by comparing it to a value that was recorded earlier.
+	Chain string
+}
+
+// Verify checks the hash chain and trailer signatures of a report
+// stream that has been written with the chain or sign option. If pub
+// is nil, signatures are checked against the public key embedded in
+// the trailer, which only protects against accidental
+// modification. Truncation, reordering, and modification of records
+// as well as removal of segments other than the last ones are
+// reported as errors.
+func Verify(r io.Reader, pub ed25519.PublicKey) (res VerifyResult, err error) {
+	var chain [sha256.Size]byte
+	var records int
+	// prev is the chain value that the current segment starts from.
+	var prev string
+	// begin starts a new segment that is linked to prev, which
+	// must be the previous segment's trailer chain value.
+	begin := func(p string) error {
+		if res.Segments > 0 && p != res.Chain {
+			return errors.New("segment is not linked to the previous one (segments removed or reordered)")
+		}
+		if res.Segments == 0 {
+			res.Prev = p
+		}
+		prev, chain = p, [sha256.Size]byte{}
+		if p != "" {
+			b, err := hex.DecodeString(p)
+			if err != nil || len(b) != sha256.Size {
+				return errors.New("invalid chain_prev value")
+			}
+			copy(chain[:], b)
+		}
+		return nil
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1<<30)
+	var line int
+	for s.Scan() {
+		line++
+		rec := s.Bytes()
+		if len(rec) == 0 {
+			continue
+		}
+		var t trailerRecord
+		if bytes.Contains(rec, []byte(`"record_type":"trailer"`)) && json.Unmarshal(rec, &t) == nil && t.RecordType == "trailer" {
+			if records == 0 {
+				if err := begin(t.Prev); err != nil {
+					return res, fmt.Errorf("line %d: %v", line, err)
+				}
+			} else if t.Prev != prev {
+				return res, fmt.Errorf("line %d: trailer chain_prev value mismatch", line)
+			}
+			if t.Records != strconv.Itoa(records) {
+				return res, fmt.Errorf("line %d: trailer claims %s records, found %d (truncated)", line, t.Records, records)
+			}
+			if t.Chain != hex.EncodeToString(chain[:]) {
+				return res, fmt.Errorf("line %d: trailer chain value mismatch", line)
+			}
+			if t.Signature != "" {
+				key := pub
+				if key == nil {
+					if key, err = base64.StdEncoding.DecodeString(t.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+						return res, fmt.Errorf("line %d: invalid public key in trailer", line)
+					}
+				}
+				sig, err := base64.StdEncoding.DecodeString(t.Signature)
+				if err != nil || !ed25519.Verify(key, trailerMessage(t), sig) {
+					return res, fmt.Errorf("line %d: invalid trailer signature", line)
+				}
+				res.Signed++
+			} else if pub != nil {
+				return res, fmt.Errorf("line %d: trailer is not signed", line)
+			}
+			res.Segments++
+			res.Records += records
+			res.Chain = t.Chain
+			records = 0
+			continue
+		}
+		if len(rec) < chainFieldLen+1 ||
+			!bytes.HasPrefix(rec[len(rec)-chainFieldLen-1:], []byte(`,"chain":"`)) ||
+			!bytes.HasSuffix(rec, []byte(`"}`)) {
+			return res, fmt.Errorf("line %d: record does not carry a chain value", line)
+		}
+		value := string(rec[len(rec)-2*sha256.Size-2 : len(rec)-2])
+		body := rec[:len(rec)-chainFieldLen-1]
+		if records == 0 {
+			var p string
+			if n := len(body) - chainPrevFieldLen; n >= 0 &&
+				bytes.HasPrefix(body[n:], []byte(`,"chain_prev":"`)) && body[len(body)-1] == '"' {
+				p = string(body[len(body)-2*sha256.Size-1 : len(body)-1])
+				body = body[:n]
+			}
+			if err := begin(p); err != nil {
+				return res, fmt.Errorf("line %d: %v", line, err)
+			}
+		}
+		orig := append(append([]byte{}, body...), '}')
+		h := sha256.New()
+		h.Write(chain[:])
+		h.Write(orig)
+		copy(chain[:], h.Sum(nil))
+		if value != hex.EncodeToString(chain[:]) {
+			return res, fmt.Errorf("line %d: chain value mismatch (record modified, removed, or reordered)", line)
+		}
+		records++
+	}
+	if err = s.Err(); err != nil {
+		return res, err
+	}
+	if records > 0 {
+		return res, fmt.Errorf("%d records after last trailer (truncated)", records)
+	}
+	if res.Segments == 0 {
+		return res, errors.New("no trailer found")
+	}
+	return res, nil
+}
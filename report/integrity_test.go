@@ -0,0 +1,103 @@
+package report
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type bufferCloser struct{ bytes.Buffer }
+
+func (bufferCloser) Close() error { return nil }
+
+func chainedReport(t *testing.T, key ed25519.PrivateKey) []string {
+	var buf bufferCloser
+	cw := &chainWriter{w: &buf, key: key}
+	f := &formatterTSJSONLines{}
+	for _, msg := range []string{"one", "two", "three"} {
+		f.formatMessage(cw, "%s", msg)
+	}
+	cw.Close()
+	return strings.SplitAfter(buf.String(), "\n")
+}
+
+func TestVerify(t *testing.T) {
+	seed := make([]byte, ed25519.SeedSize)
+	key := ed25519.NewKeyFromSeed(seed)
+	pub := key.Public().(ed25519.PublicKey)
+	lines := chainedReport(t, key)
+	if len(lines) != 5 || lines[4] != "" {
+		t.Fatalf("expected 3 records + trailer, got %q", lines)
+	}
+
+	if res, err := Verify(strings.NewReader(strings.Join(lines, "")), pub); err != nil {
+		t.Errorf("verify: %v", err)
+	} else if res.Records != 3 || res.Signed != 1 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+	// Two scan runs appended to the same file must be linked, see
+	// TestVerifyLinked.
+	twice := strings.Join(lines, "") + strings.Join(chainedReport(t, key), "")
+	if _, err := Verify(strings.NewReader(twice), pub); err == nil {
+		t.Error("verify appended report: unlinked segment was accepted")
+	}
+
+	for name, tampered := range map[string][]string{
+		"truncated":  lines[:2],
+		"no-trailer": lines[:3],
+		"reordered":  {lines[1], lines[0], lines[2], lines[3]},
+		"removed":    {lines[0], lines[2], lines[3]},
+		"modified":   {lines[0], strings.Replace(lines[1], "two", "TWO", 1), lines[2], lines[3]},
+	} {
+		if _, err := Verify(strings.NewReader(strings.Join(tampered, "")), pub); err == nil {
+			t.Errorf("%s: verification did not fail", name)
+		}
+	}
+
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	if _, err := Verify(strings.NewReader(strings.Join(chainedReport(t, other), "")), pub); err == nil {
+		t.Errorf("verification with wrong key did not fail")
+	}
+	if _, err := Verify(strings.NewReader(strings.Join(chainedReport(t, nil), "")), pub); err == nil {
+		t.Errorf("verification of unsigned report with pinned key did not fail")
+	}
+}
+
+func TestVerifyLinked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	state := filepath.Join(dir, "spyre.jsonl.chain")
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	pub := key.Public().(ed25519.PublicKey)
+	var segments []string
+	for i := 0; i < 3; i++ {
+		var buf bufferCloser
+		cw := &chainWriter{w: &buf, key: key, state: state}
+		(&formatterTSJSONLines{}).formatMessage(cw, "run %d", i)
+		cw.Close()
+		segments = append(segments, buf.String())
+	}
+	res, err := Verify(strings.NewReader(strings.Join(segments, "")), pub)
+	if err != nil || res.Segments != 3 || res.Prev != "" {
+		t.Fatalf("verify linked report: %+v, %v", res, err)
+	}
+	// Earlier segments have been rotated away
+	if res2, err := Verify(strings.NewReader(segments[1]+segments[2]), pub); err != nil || res2.Prev == "" || res2.Chain != res.Chain {
+		t.Errorf("verify last segments: %+v, %v", res2, err)
+	}
+	for name, tampered := range map[string]string{
+		"removed":   segments[0] + segments[2],
+		"reordered": segments[0] + segments[2] + segments[1],
+	} {
+		if _, err := Verify(strings.NewReader(tampered), pub); err == nil {
+			t.Errorf("%s: verification did not fail", name)
+		}
+	}
+}
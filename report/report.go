@@ -5,10 +5,16 @@ import (
 	"github.com/spyre-project/spyre/log"
 
 	"github.com/spf13/afero"
+
+	"sync"
 )
 
 var targets []target
 
+// mu serializes access to the report targets, since records may be
+// added from background goroutines (e.g. heartbeat records).
+var mu sync.Mutex
+
 func Init() error {
 	for _, spec := range config.ReportTargets {
 		tgt, err := mkTarget(spec)
@@ -17,55 +23,122 @@ func Init() error {
 		}
 		targets = append(targets, tgt)
 	}
+	if err := loadAllowlist(); err != nil {
+		return err
+	}
 	log.Noticef("Writing report to %s", config.ReportTargets)
 	return nil
 }
 
+// Encrypted reports whether any report target is encrypted.
+func Encrypted() bool {
+	for _, t := range targets {
+		if t.encrypted {
+			return true
+		}
+	}
+	return false
+}
+
 // AddStringf adds a single message with fmt.Printf-style parameters.
 func AddStringf(f string, v ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
 	for _, t := range targets {
 		t.formatMessage(t.writer, f, v...)
 	}
 }
 
 func AddFileInfo(file afero.File, description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(file, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatFileEntry(t.writer, file, description, message, extra...)
 	}
 }
 
 func AddEvtxInfo(evt string, description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatEvtxEntry(t.writer, evt, description, message, extra...)
 	}
 }
 
 func AddNetstatInfo(description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatNetstatEntry(t.writer, description, message, extra...)
 	}
 }
 
 func AddAutorunInfo(description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatAutorunEntry(t.writer, description, message, extra...)
 	}
 }
 
 func AddRegistryInfo(description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatRegistryEntry(t.writer, description, message, extra...)
 	}
 }
 
 func AddProcInfo(description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
 	for _, t := range targets {
 		t.formatProcEntry(t.writer, description, message, extra...)
 	}
 }
 
+// AddSystemInfo adds a record that is not tied to a file, process,
+// or other scanned object, such as the scan summary.
+func AddSystemInfo(description, message string, extra ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if suppress(nil, description, message, extra) {
+		return
+	}
+	countFinding(extra)
+	for _, t := range targets {
+		t.formatSystemEntry(t.writer, description, message, extra...)
+	}
+}
+
 // Close shuts down all reporting targets
 func Close() {
+	mu.Lock()
+	defer mu.Unlock()
 	for _, t := range targets {
 		t.finish(t.writer)
 		t.writer.Close()
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"sort"
+)
+
+var (
+	findings   int
+	suppressed int
+	ruleCount  = make(map[string]int)
+)
+
+// findingRule returns the rule name of a report entry. ok is false if
+// the entry is not a finding, i.e. it names no rule or describes an
+// error.
+func findingRule(extra []string) (rule string, ok bool) {
+	for it := extra; len(it) >= 2; it = it[2:] {
+		switch it[0] {
+		case "rule":
+			rule = it[1]
+		case "error":
+			return "", false
+		}
+	}
+	return rule, rule != ""
+}
+
+// countFinding records a report entry as a finding if it names a
+// rule and does not describe an error.
+func countFinding(extra []string) {
+	if rule, ok := findingRule(extra); ok {
+		findings++
+		ruleCount[rule]++
+	}
+}
+
+// Findings returns the number of findings that have been reported so
+// far.
+func Findings() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return findings
+}
+
+// Suppressed returns the number of findings that have been dropped
+// because they matched the allowlist.
+func Suppressed() int {
+	mu.Lock()
+	defer mu.Unlock()
+	return suppressed
+}
+
+// RuleCount contains the number of findings for a rule.
+type RuleCount struct {
+	Rule  string
+	Count int
+}
+
+// TopRules returns up to n rules with the most findings, in
+// descending order.
+func TopRules(n int) []RuleCount {
+	mu.Lock()
+	defer mu.Unlock()
+	var rc []RuleCount
+	for r, c := range ruleCount {
+		rc = append(rc, RuleCount{r, c})
+	}
+	sort.Slice(rc, func(i, j int) bool {
+		if rc[i].Count != rc[j].Count {
+			return rc[i].Count > rc[j].Count
+		}
+		return rc[i].Rule < rc[j].Rule
+	})
+	if len(rc) > n {
+		rc = rc[:n]
+	}
+	return rc
+}
@@ -1,11 +1,15 @@
 package report
 
 import (
+	"github.com/spyre-project/spyre/config"
+
 	"github.com/spf13/afero"
 
+	"errors"
 	"fmt"
 	"io"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
@@ -16,17 +20,23 @@ type formatter interface {
 	formatNetstatEntry(w io.Writer, description, message string, extra ...string)
 	formatAutorunEntry(w io.Writer, description, message string, extra ...string)
 	formatRegistryEntry(w io.Writer, description, message string, extra ...string)
+	formatSystemEntry(w io.Writer, description, message string, extra ...string)
 	formatMessage(w io.Writer, format string, a ...interface{})
 	finish(w io.Writer)
 }
 
 type target struct {
-	writer io.WriteCloser
+	writer    io.WriteCloser
+	encrypted bool
 	formatter
 }
 
 func mkTarget(spec string) (target, error) {
 	var t target
+	var chain bool
+	var signKey, encryptKey string
+	var fw *fileWriter
+	rotate := -1
 	for i, part := range strings.Split(spec, ",") {
 		if i == 0 {
 			var u *url.URL
@@ -43,7 +53,8 @@ func mkTarget(spec string) (target, error) {
 			}
 			switch {
 			case u.Scheme == "file":
-				t.writer = &fileWriter{path: u.Path}
+				fw = &fileWriter{path: u.Path}
+				t.writer = fw
 			default:
 				return target{}, fmt.Errorf("unrecognized scheme '%s'", u.Scheme)
 			}
@@ -53,7 +64,29 @@ func mkTarget(spec string) (target, error) {
 		if len(kv) == 1 {
 			kv = append(kv, "")
 		}
-		if kv[0] == "format" {
+		switch kv[0] {
+		case "maxsize":
+			sz, err := config.ParseFileSize(kv[1])
+			if err != nil {
+				return target{}, fmt.Errorf("maxsize: %v", err)
+			}
+			fw.maxSize = sz
+		case "rotate":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < 0 {
+				return target{}, fmt.Errorf("invalid rotate count '%s'", kv[1])
+			}
+			rotate = n
+		case "compress":
+			fw.compress = true
+		case "chain":
+			chain = true
+		case "sign":
+			chain = true
+			signKey = kv[1]
+		case "encrypt":
+			encryptKey = kv[1]
+		case "format":
 			switch kv[1] {
 			case "plain":
 				t.formatter = &formatterPlain{}
@@ -61,6 +94,12 @@ func mkTarget(spec string) (target, error) {
 				t.formatter = &formatterTSJSON{}
 			case "tsjsonl", "tsjsonlines":
 				t.formatter = &formatterTSJSONLines{}
+			case "csv":
+				t.formatter = &formatterCSV{}
+			case "html":
+				t.formatter = &formatterHTML{}
+			case "timeline":
+				t.formatter = &formatterTimeline{}
 			default:
 				return target{}, fmt.Errorf("unrecognized format %s", kv[1])
 			}
@@ -69,5 +108,53 @@ func mkTarget(spec string) (target, error) {
 	if t.formatter == nil {
 		t.formatter = &formatterTSJSONLines{}
 	}
+	if fw.maxSize > 0 {
+		// Keep 5 rotated files unless told otherwise. At least
+		// one is required, so that the report file is not
+		// removed as soon as it reaches maxsize.
+		if fw.rotate = rotate; rotate < 0 {
+			fw.rotate = 5
+		} else if rotate == 0 {
+			return target{}, errors.New("rotate must be at least 1 with maxsize")
+		}
+		// Rotation splits the output at arbitrary offsets, which
+		// would leave encrypted streams undecryptable.
+		if encryptKey != "" {
+			return target{}, errors.New("encrypt cannot be combined with maxsize")
+		}
+	} else if rotate >= 0 {
+		return target{}, errors.New("rotate requires maxsize")
+	}
+	if encryptKey != "" {
+		k, err := readEncryptionKey(encryptKey)
+		if err != nil {
+			return target{}, err
+		}
+		t.writer = &encryptWriter{w: t.writer, recipient: k}
+		t.encrypted = true
+	}
+	if chain {
+		switch t.formatter.(type) {
+		case *formatterTSJSONLines, *formatterTimeline:
+		default:
+			return target{}, errors.New("chain/sign options require a JSON lines format")
+		}
+		cw := &chainWriter{w: t.writer}
+		if fw.path != "-" {
+			// The chain value of the last trailer is kept next
+			// to the report, so that the next scan run's segment
+			// can be linked to it, even if the report is
+			// encrypted.
+			fw.expanded = expandPath(fw.path)
+			cw.state = fw.expanded + ".chain"
+		}
+		if signKey != "" {
+			var err error
+			if cw.key, err = readSigningKey(signKey); err != nil {
+				return target{}, err
+			}
+		}
+		t.writer = cw
+	}
 	return t, nil
 }
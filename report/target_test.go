@@ -2,7 +2,12 @@ package report
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/spf13/afero"
+
+	"github.com/spyre-project/spyre/config"
 )
 
 func TestTargetSpec(t *testing.T) {
@@ -25,3 +30,24 @@ func TestTargetSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestTargetEncrypted(t *testing.T) {
+	defer func(fs afero.Fs) { config.Fs = fs }(config.Fs)
+	config.Fs = afero.NewMemMapFs()
+	afero.WriteFile(config.Fs, "report.pub", []byte(strings.Repeat("07", 32)), 0644)
+	for _, spec := range []string{
+		"report.log,encrypt=report.pub",
+		"report.log,encrypt=report.pub,chain",
+	} {
+		tgt, err := mkTarget(spec)
+		if err != nil {
+			t.Fatalf("parse '%s': %v", spec, err)
+		}
+		if !tgt.encrypted {
+			t.Errorf("parse '%s': target not marked as encrypted", spec)
+		}
+	}
+	if tgt, _ := mkTarget("report.log,chain"); tgt.encrypted {
+		t.Error("unencrypted target marked as encrypted")
+	}
+}
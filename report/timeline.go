@@ -0,0 +1,114 @@
+package report
+
+import (
+	"github.com/spyre-project/spyre"
+
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// timestampFields maps the fields that scan modules use to pass
+// event timestamps (formatted using FormatTime) to the
+// Plaso/Timesketch timestamp description.
+var timestampFields = []struct{ key, desc string }{
+	{"time_modified", "Content Modification Time"},
+	{"time_accessed", "Last Access Time"},
+	{"time_changed", "Metadata Modification Time"},
+	{"time_created", "Creation Time"},
+	{"time_process_start", "Process Start Time"},
+	{"time_event", "Event Time"},
+	{"time_last_write", "Last Written Time"},
+}
+
+// FormatTime formats an event timestamp for use in one of the
+// time_* fields understood by the timeline formatter. The zero time
+// is formatted as the empty string.
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// formatterTimeline produces JSON lines suitable for import into
+// Timesketch. Unlike formatterTSJSONLines, it emits one record for
+// every event timestamp carried by a finding. Findings without any
+// event timestamp are emitted once, using the scan time.
+type formatterTimeline struct{}
+
+func (f *formatterTimeline) emitRecord(w io.Writer, t time.Time, desc string, kv ...string) {
+	r := make(map[string]string)
+	for it := kv; len(it) >= 2; it = it[2:] {
+		r[it[0]] = it[1]
+	}
+	r["timestamp"] = strconv.FormatInt(t.UnixNano()/1000, 10)
+	r["datetime"] = t.UTC().Format(time.RFC3339Nano)
+	r["timestamp_desc"] = desc
+	r["computer_name"] = spyre.Hostname
+	r["file_generator"] = "Spyre"
+	json.NewEncoder(w).Encode(r)
+}
+
+func (f *formatterTimeline) emitEntries(w io.Writer, description, message string, extra ...string) {
+	kv := append([]string{"spyre_type", description, "message", message}, extra...)
+	var emitted bool
+	for _, tf := range timestampFields {
+		for it := extra; len(it) >= 2; it = it[2:] {
+			if it[0] != tf.key || it[1] == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339Nano, it[1])
+			if err != nil {
+				continue
+			}
+			f.emitRecord(w, t, tf.desc, kv...)
+			emitted = true
+		}
+	}
+	if !emitted {
+		f.emitRecord(w, time.Now(), "Spyre Scan Time", kv...)
+	}
+}
+
+func (f *formatterTimeline) formatFileEntry(w io.Writer, file afero.File, description, message string, extra ...string) {
+	fileinfo := []string{"filename", file.Name()}
+	if fi, err := file.Stat(); err == nil {
+		fileinfo = append(fileinfo, "file_size", strconv.Itoa(int(fi.Size())))
+	}
+	f.emitEntries(w, description, message, append(fileinfo, extra...)...)
+}
+
+func (f *formatterTimeline) formatEvtxEntry(w io.Writer, evt string, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, append([]string{"evtx", evt}, extra...)...)
+}
+
+func (f *formatterTimeline) formatNetstatEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, extra...)
+}
+
+func (f *formatterTimeline) formatAutorunEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, extra...)
+}
+
+func (f *formatterTimeline) formatRegistryEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, extra...)
+}
+
+func (f *formatterTimeline) formatSystemEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, extra...)
+}
+
+func (f *formatterTimeline) formatProcEntry(w io.Writer, description, message string, extra ...string) {
+	f.emitEntries(w, description, message, extra...)
+}
+
+func (f *formatterTimeline) formatMessage(w io.Writer, format string, a ...interface{}) {
+	f.emitRecord(w, time.Now(), "Spyre Scan Time", "spyre_type", "msg", "message", fmt.Sprintf(format, a...))
+}
+
+func (f *formatterTimeline) finish(w io.Writer) {}
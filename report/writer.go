@@ -1,41 +1,170 @@
 package report
 
 import (
+	"github.com/spyre-project/spyre"
 	"github.com/spyre-project/spyre/log"
 
+	"compress/gzip"
+	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
+// writeRetries is the number of times a failed write is retried
+// (after reopening the report file) before the data is dropped. While
+// the report file is known to be unwritable, each write is only tried
+// once so that the scan is not slowed down.
+const writeRetries = 3
+
 type fileWriter struct {
 	path     string
-	disabled bool
+	maxSize  int64
+	rotate   int
+	compress bool
+
 	w        io.WriteCloser
+	expanded string
+	size     int64
+	failing  bool
 }
 
-func (fw *fileWriter) Write(buf []byte) (n int, e error) {
-	n, e = len(buf), nil
-	if fw.disabled {
+// expandPath replaces the placeholders {hostname}, {date}, and
+// {time} in a report file name.
+func expandPath(path string) string {
+	now := time.Now()
+	return strings.NewReplacer(
+		"{hostname}", spyre.Hostname,
+		"{date}", now.Format("20060102"),
+		"{time}", now.Format("150405"),
+	).Replace(path)
+}
+
+func (fw *fileWriter) open() (err error) {
+	if fw.path == "-" {
+		fw.w = os.Stdout
+		return nil
+	}
+	if fw.expanded == "" {
+		fw.expanded = expandPath(fw.path)
+	}
+	const mode = os.O_APPEND | os.O_WRONLY | os.O_CREATE
+	f, err := os.OpenFile(fw.expanded, mode, 0666)
+	if err != nil {
+		return err
+	}
+	fw.size = 0
+	if fi, err := f.Stat(); err == nil {
+		fw.size = fi.Size()
+	}
+	fw.w = f
+	return nil
+}
+
+func (fw *fileWriter) rotatedName(i int) string {
+	name := fmt.Sprintf("%s.%d", fw.expanded, i)
+	if fw.compress {
+		name += ".gz"
+	}
+	return name
+}
+
+// rotateFiles shifts existing rotated files, deleting the oldest one
+// if fw.rotate files already exist, and moves the current report file
+// out of the way, compressing it if requested.
+func (fw *fileWriter) rotateFiles() {
+	fw.w.Close()
+	fw.w = nil
+	os.Remove(fw.rotatedName(fw.rotate))
+	for i := fw.rotate - 1; i >= 1; i-- {
+		os.Rename(fw.rotatedName(i), fw.rotatedName(i+1))
+	}
+	if !fw.compress {
+		if err := os.Rename(fw.expanded, fw.rotatedName(1)); err != nil {
+			log.Errorf("Could not rotate report file %s: %s", fw.expanded, err)
+		}
+		return
+	}
+	if err := gzipFile(fw.expanded, fw.rotatedName(1)); err != nil {
+		log.Errorf("Could not compress report file %s: %s", fw.expanded, err)
 		return
 	}
+	os.Remove(fw.expanded)
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err = io.Copy(zw, in); err == nil {
+		err = zw.Close()
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// Write writes buf to the report file, opening and rotating it as
+// needed. Failed writes are retried after reopening the file; if
+// the report file stays unwritable, the data is dropped, but
+// subsequent writes will try again.
+func (fw *fileWriter) Write(buf []byte) (int, error) {
 	var err error
-	if fw.w == nil {
-		const mode = os.O_APPEND | os.O_WRONLY | os.O_CREATE | os.O_APPEND
-		if fw.path == "-" {
-			fw.w = os.Stdout
-		} else if fw.w, err = os.OpenFile(fw.path, mode, 0666); err != nil {
-			log.Errorf("Could not open report file %s: %s", fw.path, err)
-			fw.disabled = true
-			return
+	size, retries := len(buf), writeRetries
+	if fw.failing {
+		retries = 0
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+		if fw.w == nil {
+			if err = fw.open(); err != nil {
+				continue
+			}
+		}
+		if fw.maxSize > 0 && fw.path != "-" && fw.size > 0 && fw.size+int64(len(buf)) > fw.maxSize {
+			fw.rotateFiles()
+			if err = fw.open(); err != nil {
+				continue
+			}
 		}
+		var n int
+		n, err = fw.w.Write(buf)
+		fw.size += int64(n)
+		if err == nil {
+			if fw.failing {
+				log.Noticef("Report file %s is writable again", fw.expanded)
+				fw.failing = false
+			}
+			return size, nil
+		}
+		if fw.w != os.Stdout {
+			fw.w.Close()
+			fw.w = nil
+		}
+		// Retry only the part that has not been written.
+		buf = buf[n:]
 	}
-	if n, err = fw.w.Write(buf); err != nil {
-		log.Errorf("Could not write to report file %s: %s", fw.path, err)
-		fw.disabled = true
-		fw.w.Close()
-		fw.w = nil
+	if !fw.failing {
+		log.Errorf("Could not write to report file %s, dropping output: %s", fw.path, err)
+		fw.failing = true
 	}
-	return
+	// The error is not propagated since the formatters do not
+	// handle errors; dropped data has been logged.
+	return size, nil
 }
 
 func (fw *fileWriter) Close() error {
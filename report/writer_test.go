@@ -0,0 +1,95 @@
+package report
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWriterRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tgt, err := mkTarget(filepath.Join(dir, "report-{date}.log") + ",maxsize=16B,rotate=2,compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw := tgt.writer.(*fileWriter)
+	for _, line := range []string{"0123456789\n", "abcdefghij\n", "ABCDEFGHIJ\n", "klmnopqrst\n"} {
+		fw.Write([]byte(line))
+	}
+	fw.Close()
+
+	buf, err := ioutil.ReadFile(fw.expanded)
+	if err != nil || string(buf) != "klmnopqrst\n" {
+		t.Errorf("current file: %q, %v", buf, err)
+	}
+	for i, expected := range []string{"ABCDEFGHIJ\n", "abcdefghij\n"} {
+		f, err := os.Open(fw.rotatedName(i + 1))
+		if err != nil {
+			t.Errorf("rotated file %d: %v", i+1, err)
+			continue
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Errorf("rotated file %d: %v", i+1, err)
+		} else if buf, _ := ioutil.ReadAll(zr); string(buf) != expected {
+			t.Errorf("rotated file %d: got %q, expected %q", i+1, buf, expected)
+		}
+		f.Close()
+	}
+	if _, err := os.Stat(fw.rotatedName(3)); err == nil {
+		t.Errorf("more than 2 rotated files were kept")
+	}
+}
+
+func TestFileWriterFailing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fw := &fileWriter{path: filepath.Join(dir, "missing", "report.log")}
+	line := []byte("0123456789\n")
+	if n, err := fw.Write(line); n != len(line) || err != nil {
+		t.Errorf("Write: got %d, %v; expected %d, nil", n, err, len(line))
+	}
+	if !fw.failing {
+		t.Fatal("writer is not marked as failing")
+	}
+	start := time.Now()
+	if n, err := fw.Write(line); n != len(line) || err != nil {
+		t.Errorf("Write: got %d, %v; expected %d, nil", n, err, len(line))
+	}
+	if d := time.Since(start); d >= 100*time.Millisecond {
+		t.Errorf("write to failing report file was retried (%v)", d)
+	}
+	os.Mkdir(filepath.Join(dir, "missing"), 0755)
+	fw.Write(line)
+	fw.Close()
+	if fw.failing {
+		t.Error("writer is still marked as failing")
+	}
+	if buf, err := ioutil.ReadFile(fw.path); err != nil || string(buf) != string(line) {
+		t.Errorf("report file: %q, %v", buf, err)
+	}
+}
+
+func TestRotateRequired(t *testing.T) {
+	for _, spec := range []string{
+		"report.log,maxsize=1MB,rotate=0",
+		"report.log,rotate=3",
+		"report.log,maxsize=1MB,encrypt=report.pub",
+	} {
+		if _, err := mkTarget(spec); err == nil {
+			t.Errorf("%s was accepted", spec)
+		}
+	}
+}
@@ -17,7 +17,9 @@ import (
 	"encoding/csv"
 	"io"
 	"net"
+	"time"
 	"github.com/spyre-project/spyre"
+	"github.com/spyre-project/spyre/baseline"
 	"github.com/spyre-project/spyre/config"
 	"github.com/spyre-project/spyre/log"
 	"github.com/spyre-project/spyre/report"
@@ -19877,6 +19879,17 @@ func (s *systemScanner) Init() error {
 	return nil
 }
 
+// autorunTime converts the timestamp found in the Time column of
+// autorunsc's CSV output to the format expected by the report
+// package.
+func autorunTime(s string) string {
+	t, err := time.ParseInLocation("20060102-150405", s, time.UTC)
+	if err != nil {
+		return ""
+	}
+	return report.FormatTime(t)
+}
+
 func DecodeUTF16(b []byte) (string, error) {
 
 	if len(b)%2 != 0 {
@@ -20043,6 +20056,7 @@ func (s *systemScanner) Scan() error {
     			outStr = string(stdout)
     	}
     	os.Remove(tmpFile.Name())
+    	baseline.Collecting("autorun")
       scanner := bufio.NewScanner(strings.NewReader(outStr))
       for scanner.Scan() {
           line_val := scanner.Text()
@@ -20140,12 +20154,16 @@ func (s *systemScanner) Scan() error {
     				  }
 
     	    }
+    	    if csv_entry != "unknown" {
+    	      baseline.Record("autorun", csv_entryloc+"\\"+csv_entry,
+    	        fmt.Sprintf("image=%s launch=%s sha256=%s signer=%s", csv_image_path, csv_launch_string, csv_sha256, csv_signer))
+    	    }
     	    for _, ioc := range s.iocs {
             if ioc.Type == 0 {
               if strings.Contains(line_val, ioc.Value) {
     						message := fmt.Sprintf("Found autorunsc rule: %s on %s",ioc.Description, line_val)
     						report.AddNetstatInfo("ioc_on_autorun", message,
-    							"rule", ioc.Description, "real_date", csv_time, "entry_location", csv_entryloc,
+    							"rule", ioc.Description, "real_date", csv_time, "time_last_write", autorunTime(csv_time), "entry_location", csv_entryloc,
     						  "entry", csv_entry, "enabled", csv_enabled, "autorun_type", csv_category,
     							"profile", csv_profile, "autorun_desc", csv_description, "autorun_signed", csv_signer,
     							"autorun_company", csv_company, "image_file", csv_image_path,
@@ -20157,7 +20175,7 @@ func (s *systemScanner) Scan() error {
               if !(strings.Contains(line_val, ioc.Value)) {
     						message := fmt.Sprintf("Found autorunsc rule: %s on %s",ioc.Description, line_val)
     						report.AddNetstatInfo("ioc_on_autorun", message,
-    							"rule", ioc.Description, "real_date", csv_time, "entry_location", csv_entryloc,
+    							"rule", ioc.Description, "real_date", csv_time, "time_last_write", autorunTime(csv_time), "entry_location", csv_entryloc,
     						  "entry", csv_entry, "enabled", csv_enabled, "autorun_type", csv_category,
     							"profile", csv_profile, "autorun_desc", csv_description, "autorun_signed", csv_signer,
     							"autorun_company", csv_company, "image_file", csv_image_path,
@@ -20174,7 +20192,7 @@ func (s *systemScanner) Scan() error {
     					if matched {
     						message := fmt.Sprintf("Found autorunsc rule: %s on %s",ioc.Description, line_val)
     						report.AddNetstatInfo("ioc_on_autorun", message,
-    							"rule", ioc.Description, "real_date", csv_time, "entry_location", csv_entryloc,
+    							"rule", ioc.Description, "real_date", csv_time, "time_last_write", autorunTime(csv_time), "entry_location", csv_entryloc,
     						  "entry", csv_entry, "enabled", csv_enabled, "autorun_type", csv_category,
     							"profile", csv_profile, "autorun_desc", csv_description, "autorun_signed", csv_signer,
     							"autorun_company", csv_company, "image_file", csv_image_path,
@@ -20191,7 +20209,7 @@ func (s *systemScanner) Scan() error {
     					if !(matched) {
     						message := fmt.Sprintf("Found autorunsc rule: %s on %s",ioc.Description, line_val)
     						report.AddNetstatInfo("ioc_on_autorun", message,
-    							"rule", ioc.Description, "real_date", csv_time, "entry_location", csv_entryloc,
+    							"rule", ioc.Description, "real_date", csv_time, "time_last_write", autorunTime(csv_time), "entry_location", csv_entryloc,
     						  "entry", csv_entry, "enabled", csv_enabled, "autorun_type", csv_category,
     							"profile", csv_profile, "autorun_desc", csv_description, "autorun_signed", csv_signer,
     							"autorun_company", csv_company, "image_file", csv_image_path,
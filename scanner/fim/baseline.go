@@ -0,0 +1,90 @@
+package fim
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const baselineMagic = "SPYREFIM1"
+
+// entry describes the state of a single file.
+type entry struct {
+	Hash, Mode, Owner string
+	Size              int64
+}
+
+// baseline maps file paths to their recorded state. It is stored as
+// a header line, one tab-separated line per file, and an optional
+// Ed25519 signature over all preceding lines.
+type baseline map[string]entry
+
+func (b baseline) marshal(key ed25519.PrivateKey) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(baselineMagic + "\n")
+	var paths []string
+	for p := range b {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		e := b[p]
+		fmt.Fprintf(&buf, "%s\t%s\t%s\t%d\t%s\n", e.Hash, e.Mode, e.Owner, e.Size, strconv.Quote(p))
+	}
+	if key != nil {
+		sig := ed25519.Sign(key, buf.Bytes())
+		fmt.Fprintf(&buf, "signature\t%s\n", base64.StdEncoding.EncodeToString(sig))
+	}
+	return buf.Bytes()
+}
+
+// parseBaseline parses a baseline. If pub is set, the baseline must
+// carry a valid signature by that key.
+func parseBaseline(buf []byte, pub ed25519.PublicKey) (baseline, error) {
+	body := buf
+	var sig []byte
+	if i := bytes.LastIndex(buf, []byte("\nsignature\t")); i >= 0 {
+		body = buf[:i+1]
+		var err error
+		sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(buf[i+len("\nsignature\t"):])))
+		if err != nil {
+			return nil, errors.New("malformed signature")
+		}
+	}
+	if pub != nil {
+		if sig == nil {
+			return nil, errors.New("baseline is not signed")
+		}
+		if !ed25519.Verify(pub, body, sig) {
+			return nil, errors.New("invalid baseline signature")
+		}
+	}
+	b := make(baseline)
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(nil, 1024*1024)
+	if !sc.Scan() || sc.Text() != baselineMagic {
+		return nil, errors.New("not a file integrity baseline")
+	}
+	for sc.Scan() {
+		f := strings.SplitN(sc.Text(), "\t", 5)
+		if len(f) != 5 {
+			return nil, fmt.Errorf("invalid line: %q", sc.Text())
+		}
+		size, err := strconv.ParseInt(f[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size: %q", sc.Text())
+		}
+		path, err := strconv.Unquote(f[4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid path: %q", sc.Text())
+		}
+		b[path] = entry{Hash: f[0], Mode: f[1], Owner: f[2], Size: size}
+	}
+	return b, sc.Err()
+}
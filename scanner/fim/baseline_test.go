@@ -0,0 +1,38 @@
+package fim
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"reflect"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	key := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	pub := key.Public().(ed25519.PublicKey)
+	b := baseline{
+		"/etc/passwd":    {Hash: "aa", Mode: "-rw-r--r--", Owner: "0:0", Size: 1234},
+		"/etc/odd\tname": {Hash: "bb", Mode: "-rwxr-xr-x", Owner: "0:0", Size: 1},
+	}
+
+	unsigned := b.marshal(nil)
+	if got, err := parseBaseline(unsigned, nil); err != nil || !reflect.DeepEqual(got, b) {
+		t.Errorf("unsigned: got %v, %v", got, err)
+	}
+	if _, err := parseBaseline(unsigned, pub); err == nil {
+		t.Errorf("unsigned baseline accepted with verification key")
+	}
+
+	signed := b.marshal(key)
+	if got, err := parseBaseline(signed, pub); err != nil || !reflect.DeepEqual(got, b) {
+		t.Errorf("signed: got %v, %v", got, err)
+	}
+	tampered := bytes.Replace(signed, []byte("aa"), []byte("cc"), 1)
+	if _, err := parseBaseline(tampered, pub); err == nil {
+		t.Errorf("tampered baseline accepted")
+	}
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	if _, err := parseBaseline(b.marshal(other), pub); err == nil {
+		t.Errorf("baseline signed by wrong key accepted")
+	}
+}
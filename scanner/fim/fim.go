@@ -0,0 +1,290 @@
+// Package fim implements file integrity monitoring: files below
+// configured critical paths are hashed and compared against a
+// baseline from an earlier scan.
+package fim
+
+import (
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/platform"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+
+	"github.com/spf13/afero"
+
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() { scanner.RegisterFileScanner(&fileScanner{}) }
+
+type fileScanner struct {
+	paths    []string
+	baseline baseline
+	// invalid is set if the baseline could not be read or verified.
+	invalid error
+	signKey ed25519.PrivateKey
+	seen    baseline
+}
+
+func (s *fileScanner) Name() string { return "FIM" }
+
+// Init reads the baseline. Problems with the baseline are only
+// reported at the end of the file scan, since a failing Init would
+// disable file scanning altogether.
+func (s *fileScanner) Init() error {
+	for _, p := range config.FimPaths {
+		s.paths = append(s.paths, filepath.Clean(p))
+	}
+	if len(s.paths) == 0 {
+		return nil
+	}
+	s.seen = make(baseline)
+	var pub ed25519.PublicKey
+	if config.FimVerifyKey != "" {
+		buf, err := afero.ReadFile(config.Fs, config.FimVerifyKey)
+		if err == nil {
+			pub, err = report.ParsePublicKey(buf)
+		}
+		if err != nil {
+			s.invalid = fmt.Errorf("FIM verification key %s: %v", config.FimVerifyKey, err)
+			return nil
+		}
+	}
+	if config.FimSignKey != "" {
+		buf, err := afero.ReadFile(config.Fs, config.FimSignKey)
+		if err == nil {
+			s.signKey, err = report.ParsePrivateKey(buf)
+		}
+		if err != nil {
+			log.Errorf("FIM signing key %s: %v", config.FimSignKey, err)
+		}
+	}
+	buf, err := readBaseline()
+	if os.IsNotExist(err) {
+		log.Noticef("No file integrity baseline found, a new one will be created")
+		return nil
+	} else if err != nil {
+		s.invalid = err
+		return nil
+	}
+	if s.baseline, err = parseBaseline(buf, pub); err != nil {
+		s.invalid = fmt.Errorf("%s: %v", config.FimBaseline, err)
+	}
+	return nil
+}
+
+func (s *fileScanner) covers(path string) bool {
+	for _, p := range s.paths {
+		if path == p || strings.HasPrefix(path, p+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MustScan makes sure that files below the critical paths are hashed
+// in every scan.
+func (s *fileScanner) MustScan(path string) bool { return s.covers(path) }
+
+func fileEntry(f io.Reader, fi os.FileInfo) (entry, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return entry{}, err
+	}
+	uid, gid := platform.FileOwner(fi)
+	return entry{
+		Hash:  hex.EncodeToString(h.Sum(nil)),
+		Mode:  fi.Mode().String(),
+		Owner: uid + ":" + gid,
+		Size:  fi.Size(),
+	}, nil
+}
+
+func (s *fileScanner) ScanFile(f afero.File) error {
+	path := f.Name()
+	if !s.covers(path) {
+		return scanner.ErrSkipped
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	e, err := fileEntry(f, fi)
+	if err != nil {
+		return err
+	}
+	s.check(path, e)
+	return nil
+}
+
+// check records a file and compares it to the baseline.
+func (s *fileScanner) check(path string, e entry) {
+	s.seen[path] = e
+	if s.baseline == nil {
+		return
+	}
+	if old, ok := s.baseline[path]; !ok {
+		reportChange(path, "added", entry{}, e)
+	} else if old != e {
+		reportChange(path, "modified", old, e)
+	}
+}
+
+// walk checks the files below the critical paths that have not been
+// seen during the file walk, e.g. because they are outside of --path
+// or larger than --max-file-size.
+func (s *fileScanner) walk() {
+	for _, root := range s.paths {
+		filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				log.Errorf("FIM: %s: %v", path, err)
+				return nil
+			}
+			if _, ok := s.seen[path]; ok || !fi.Mode().IsRegular() {
+				return nil
+			}
+			e, err := statEntry(path)
+			if err != nil {
+				log.Errorf("FIM: %s: %v", path, err)
+				return nil
+			}
+			s.check(path, e)
+			return nil
+		})
+	}
+}
+
+// Finish checks the files below the critical paths that have not been
+// seen during the file walk, then files from the baseline that have
+// been deleted, and writes a new baseline if needed.
+func (s *fileScanner) Finish() error {
+	if len(s.paths) == 0 {
+		return nil
+	}
+	if s.invalid != nil {
+		log.Errorf("File integrity baseline not usable: %v", s.invalid)
+		report.AddSystemInfo("fim_change", "File integrity baseline not usable",
+			"error", s.invalid.Error(), "fim_baseline", config.FimBaseline)
+		if !config.FimUpdate {
+			return s.invalid
+		}
+	}
+	s.walk()
+	if len(s.seen) == 0 {
+		log.Warnf("FIM: no files found below %s", strings.Join(s.paths, ", "))
+	}
+	var paths []string
+	for path := range s.baseline {
+		if _, ok := s.seen[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		old := s.baseline[path]
+		e, err := statEntry(path)
+		if os.IsNotExist(err) {
+			reportChange(path, "deleted", old, entry{})
+			continue
+		} else if err != nil {
+			log.Errorf("FIM: %s: %v", path, err)
+			s.seen[path] = old
+			continue
+		}
+		s.seen[path] = e
+		if old != e {
+			reportChange(path, "modified", old, e)
+		}
+	}
+	if s.baseline != nil && !config.FimUpdate {
+		return nil
+	}
+	return s.save()
+}
+
+func statEntry(path string) (entry, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return entry{}, err
+	}
+	if !fi.Mode().IsRegular() {
+		return entry{Mode: fi.Mode().String()}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return entry{}, err
+	}
+	defer f.Close()
+	return fileEntry(f, fi)
+}
+
+// baselinePath returns the location that the baseline is written
+// to: next to the Spyre binary, since the configuration may be
+// read-only (e.g. an appended ZIP file).
+func baselinePath() string {
+	path := config.FimBaseline
+	if !filepath.IsAbs(path) {
+		exe, _ := filepath.Abs(os.Args[0])
+		path = filepath.Join(filepath.Dir(exe), path)
+	}
+	return path
+}
+
+// readBaseline reads the baseline that has been written by a previous
+// scan, falling back to the configuration.
+func readBaseline() ([]byte, error) {
+	buf, err := ioutil.ReadFile(baselinePath())
+	if err == nil || !os.IsNotExist(err) {
+		return buf, err
+	}
+	return afero.ReadFile(config.Fs, config.FimBaseline)
+}
+
+// save writes the new baseline, see baselinePath. An unsigned
+// baseline is not written if signatures are verified, since it would
+// be rejected by the next scan.
+func (s *fileScanner) save() error {
+	if config.FimVerifyKey != "" && s.signKey == nil {
+		return errors.New("not writing unsigned file integrity baseline: --fim-verify-key is set, but no usable --fim-sign-key")
+	}
+	path := baselinePath()
+	if err := ioutil.WriteFile(path, s.seen.marshal(s.signKey), 0600); err != nil {
+		return fmt.Errorf("write file integrity baseline: %v", err)
+	}
+	log.Noticef("Wrote file integrity baseline with %d files to %s", len(s.seen), path)
+	return nil
+}
+
+func reportChange(path, change string, old, new entry) {
+	message := fmt.Sprintf("File %s since integrity baseline: %s", change, path)
+	report.AddSystemInfo("fim_change", message,
+		"rule", "fim_"+change,
+		"change", change,
+		"Filepath", path,
+		"old_sha256", old.Hash, "new_sha256", new.Hash,
+		"old_mode", old.Mode, "new_mode", new.Mode,
+		"old_owner", old.Owner, "new_owner", new.Owner,
+		"old_size", sizeString(old), "new_size", sizeString(new),
+	)
+}
+
+func sizeString(e entry) string {
+	if e.Hash == "" {
+		return ""
+	}
+	return strconv.FormatInt(e.Size, 10)
+}
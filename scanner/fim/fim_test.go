@@ -0,0 +1,52 @@
+package fim
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+func TestFinish(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	tr.Write("etc/passwd", "root:x:0:0:root:/root:/bin/sh\n")
+	tr.Write("etc/ssh/sshd_config", "PermitRootLogin no\n")
+	defer func(baseline, verifyKey string) {
+		config.FimBaseline, config.FimVerifyKey = baseline, verifyKey
+	}(config.FimBaseline, config.FimVerifyKey)
+	config.FimBaseline = tr.Path("fim.baseline")
+
+	// Files outside of the file walk are picked up.
+	s := &fileScanner{paths: []string{tr.Path("etc")}, seen: make(baseline)}
+	if err := s.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := ioutil.ReadFile(config.FimBaseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parseBaseline(buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"etc/passwd", "etc/ssh/sshd_config"} {
+		if _, ok := b[tr.Path(name)]; !ok {
+			t.Errorf("%s missing from baseline", name)
+		}
+	}
+
+	// Unsigned baselines are not written if they would fail
+	// verification.
+	config.FimVerifyKey = "fim.pub"
+	config.FimBaseline = tr.Path("unsigned.baseline")
+	s = &fileScanner{paths: []string{tr.Path("etc")}, seen: make(baseline)}
+	if err := s.Finish(); err == nil {
+		t.Error("unsigned baseline was written with verification key set")
+	}
+	if _, err := os.Stat(config.FimBaseline); !os.IsNotExist(err) {
+		t.Errorf("unsigned baseline: %v", err)
+	}
+}
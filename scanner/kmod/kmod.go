@@ -0,0 +1,288 @@
+// +build linux
+
+package kmod
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spyre-project/spyre/baseline"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+)
+
+func init() {
+	scanner.RegisterSystemScanner(&systemScanner{procPath: "/proc", sysPath: "/sys"})
+}
+
+// systemScanner inventories loaded kernel modules and looks for
+// modules that have been hidden from /proc/modules or /sys/module.
+type systemScanner struct {
+	procPath, sysPath string
+	iocs              []eventIOC
+}
+
+type eventIOC struct {
+	Names       []string `json:"names"`
+	Regex       string   `json:"regex"`
+	Description string   `json:"description"`
+	re          *regexp.Regexp
+}
+
+type iocFile struct {
+	Keys []eventIOC `json:"kernel_modules"`
+}
+
+func (s *systemScanner) Name() string { return "Kernel-Modules" }
+
+func (s *systemScanner) Init() error {
+	iocFiles := config.IocFiles
+	if len(iocFiles) == 0 {
+		iocFiles = []string{"ioc.json"}
+	}
+	for _, file := range iocFiles {
+		var current iocFile
+		if err := config.ReadIOCs(file, &current); err != nil {
+			log.Error(err.Error())
+		}
+		for _, ioc := range current.Keys {
+			if ioc.Regex != "" {
+				re, err := regexp.Compile(ioc.Regex)
+				if err != nil {
+					log.Noticef("Error regexp in kernel module rule %s: %s", ioc.Description, err)
+					continue
+				}
+				ioc.re = re
+			}
+			s.iocs = append(s.iocs, ioc)
+		}
+	}
+	return nil
+}
+
+func (ioc *eventIOC) match(name string) bool {
+	for _, n := range ioc.Names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return ioc.re != nil && ioc.re.MatchString(name)
+}
+
+// module describes a loadable kernel module as seen in /proc/modules
+// and/or /sys/module.
+type module struct {
+	Name    string
+	Size    string
+	State   string
+	Taint   string // taint flags, e.g. "OE"
+	InProc  bool
+	InSysfs bool
+}
+
+// taintFlags lists the kernel taint flags by bit number, see
+// Documentation/admin-guide/tainted-kernels.rst.
+var taintFlags = []struct{ letter, description string }{
+	{"P", "proprietary module was loaded"},
+	{"F", "module was force loaded"},
+	{"S", "kernel running on an out of specification system"},
+	{"R", "module was force unloaded"},
+	{"M", "processor reported a machine check exception"},
+	{"B", "bad page referenced or unexpected page flags"},
+	{"U", "taint requested by userspace application"},
+	{"D", "kernel died recently (OOPS or BUG)"},
+	{"A", "ACPI table overridden by user"},
+	{"W", "kernel issued warning"},
+	{"C", "staging driver was loaded"},
+	{"I", "workaround for bug in platform firmware applied"},
+	{"O", "externally-built (out-of-tree) module was loaded"},
+	{"E", "unsigned module was loaded"},
+	{"L", "soft lockup occurred"},
+	{"K", "kernel has been live patched"},
+	{"X", "auxiliary taint, defined for and used by distros"},
+	{"T", "kernel was built with the struct randomization plugin"},
+	{"N", "an in-kernel test has been run"},
+}
+
+// decodeTaint returns the letters and descriptions of the flags set
+// in the kernel taint bitmask.
+func decodeTaint(mask uint64) (letters string, descriptions []string) {
+	for bit, f := range taintFlags {
+		if mask&(1<<uint(bit)) != 0 {
+			letters += f.letter
+			descriptions = append(descriptions, f.description)
+		}
+	}
+	return
+}
+
+// readProcModules parses /proc/modules.
+func (s *systemScanner) readProcModules(modules map[string]*module) error {
+	f, err := os.Open(filepath.Join(s.procPath, "modules"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// name size refcount deps state address [(taint)]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		m := &module{Name: fields[0], Size: fields[1], State: fields[4], InProc: true}
+		if last := fields[len(fields)-1]; strings.HasPrefix(last, "(") && strings.HasSuffix(last, ")") {
+			m.Taint = strings.Trim(last, "()")
+		}
+		modules[m.Name] = m
+	}
+	return scanner.Err()
+}
+
+func readAttr(path string) string {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(buf))
+}
+
+// readSysModules adds loadable modules found in /sys/module. Built-in
+// modules, which do not have an initstate attribute, are skipped.
+func (s *systemScanner) readSysModules(modules map[string]*module) error {
+	dir := filepath.Join(s.sysPath, "module")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := os.Stat(filepath.Join(dir, e.Name(), "initstate")); err != nil {
+			continue
+		}
+		m, ok := modules[e.Name()]
+		if !ok {
+			m = &module{Name: e.Name()}
+			modules[m.Name] = m
+		}
+		m.InSysfs = true
+		if m.State == "" {
+			m.State = readAttr(filepath.Join(dir, e.Name(), "initstate"))
+		}
+		if m.Size == "" {
+			m.Size = readAttr(filepath.Join(dir, e.Name(), "coresize"))
+		}
+		if taint := readAttr(filepath.Join(dir, e.Name(), "taint")); taint != "" && m.Taint == "" {
+			m.Taint = taint
+		}
+	}
+	return nil
+}
+
+// inventory returns the kernel modules found in /proc/modules and
+// /sys/module, sorted by name.
+func (s *systemScanner) inventory() ([]*module, error) {
+	modules := make(map[string]*module)
+	if err := s.readProcModules(modules); err != nil {
+		return nil, err
+	}
+	if err := s.readSysModules(modules); err != nil {
+		return nil, err
+	}
+	var list []*module
+	for _, m := range modules {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+type finding struct {
+	message string
+	extra   []string
+}
+
+// check returns findings for a single module.
+func (s *systemScanner) check(m *module) (findings []finding) {
+	add := func(rule, message string) {
+		findings = append(findings, finding{
+			message: fmt.Sprintf("Kernel module %s: %s", m.Name, message),
+			extra: []string{
+				"rule", rule,
+				"module", m.Name,
+				"size", m.Size,
+				"state", m.State,
+				"taint", m.Taint,
+			},
+		})
+	}
+	switch {
+	case m.InProc && !m.InSysfs:
+		add("kernel_module_hidden_sysfs", "listed in /proc/modules but missing from /sys/module")
+	case m.InSysfs && !m.InProc:
+		add("kernel_module_hidden_procfs", "present in /sys/module but missing from /proc/modules")
+	}
+	if strings.Contains(m.Taint, "E") {
+		add("kernel_module_unsigned", "module is not signed")
+	}
+	if strings.Contains(m.Taint, "O") {
+		add("kernel_module_out_of_tree", "module is out-of-tree")
+	}
+	for i := range s.iocs {
+		if s.iocs[i].match(m.Name) {
+			add(s.iocs[i].Description, "matched kernel module rule")
+		}
+	}
+	return
+}
+
+// checkTaint returns a finding if the kernel is tainted.
+func (s *systemScanner) checkTaint() []finding {
+	value := readAttr(filepath.Join(s.procPath, "sys", "kernel", "tainted"))
+	if value == "" {
+		return nil
+	}
+	mask, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Errorf("Could not parse kernel taint flags %q: %v", value, err)
+		return nil
+	}
+	if mask == 0 {
+		return nil
+	}
+	letters, descriptions := decodeTaint(mask)
+	return []finding{{
+		message: fmt.Sprintf("Kernel is tainted (%s): %s", letters, strings.Join(descriptions, ", ")),
+		extra: []string{
+			"rule", "kernel_tainted",
+			"taint", letters,
+			"taint_mask", value,
+		},
+	}}
+}
+
+func (s *systemScanner) Scan() error {
+	modules, err := s.inventory()
+	if err != nil {
+		return err
+	}
+	log.Infof("Found %d kernel modules", len(modules))
+	baseline.Collecting("kernel_module")
+	findings := s.checkTaint()
+	for _, m := range modules {
+		baseline.Record("kernel_module", m.Name, fmt.Sprintf("size=%s taint=%s", m.Size, m.Taint))
+		findings = append(findings, s.check(m)...)
+	}
+	for _, f := range findings {
+		report.AddSystemInfo("kernel_module", f.message, f.extra...)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+// +build linux
+
+package kmod
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+// field returns the value of a field of a finding.
+func field(f finding, key string) string {
+	for i := 0; i+1 < len(f.extra); i += 2 {
+		if f.extra[i] == key {
+			return f.extra[i+1]
+		}
+	}
+	return ""
+}
+
+func TestScan(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	tr.Write("proc/modules",
+		"ext4 749568 1 - Live 0xffffffffc0400000\n"+
+			"vboxdrv 524288 2 vboxnetadp,vboxnetflt, Live 0xffffffffc0600000 (OE)\n"+
+			"ghost 16384 0 - Live 0xffffffffc0800000\n")
+	tr.Write("proc/sys/kernel/tainted", "12288\n")
+	tr.Write("sys/module/ext4/initstate", "live\n")
+	tr.Write("sys/module/vboxdrv/initstate", "live\n")
+	tr.Write("sys/module/vboxdrv/taint", "OE\n")
+	tr.Write("sys/module/diamorphine/initstate", "live\n")
+	tr.Write("sys/module/diamorphine/coresize", "16384\n")
+	tr.Write("sys/module/diamorphine/taint", "OE\n")
+	// built-in module without initstate
+	tr.Write("sys/module/kernel/parameters/panic", "0\n")
+
+	s := &systemScanner{
+		procPath: tr.Path("proc"),
+		sysPath:  tr.Path("sys"),
+		iocs: []eventIOC{
+			{Names: []string{"Diamorphine", "reptile"}, Description: "rootkit_module"},
+			{Regex: "^vbox", Description: "virtualbox_module", re: regexp.MustCompile("^vbox")},
+		},
+	}
+	modules, err := s.inventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var got []string
+	for _, m := range modules {
+		names = append(names, m.Name)
+		for _, f := range s.check(m) {
+			got = append(got, m.Name+" "+field(f, "rule"))
+		}
+	}
+	if expected := []string{"diamorphine", "ext4", "ghost", "vboxdrv"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("modules: got %v, expected %v", names, expected)
+	}
+	expected := []string{
+		"diamorphine kernel_module_hidden_procfs",
+		"diamorphine kernel_module_unsigned",
+		"diamorphine kernel_module_out_of_tree",
+		"diamorphine rootkit_module",
+		"ghost kernel_module_hidden_sysfs",
+		"vboxdrv kernel_module_unsigned",
+		"vboxdrv kernel_module_out_of_tree",
+		"vboxdrv virtualbox_module",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("findings:\ngot      %q\nexpected %q", got, expected)
+	}
+
+	taint := s.checkTaint()
+	if len(taint) != 1 || field(taint[0], "taint") != "OE" {
+		t.Errorf("taint: got %v", taint)
+	}
+}
@@ -0,0 +1,276 @@
+// +build linux
+
+package ldpreload
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+)
+
+func init() { scanner.RegisterSystemScanner(&systemScanner{root: "/", proc: procfs.Default}) }
+
+// systemScanner looks for dynamic linker configuration that is
+// commonly abused by userland rootkits to inject libraries into
+// processes.
+type systemScanner struct {
+	root string
+	proc procfs.FS
+}
+
+// finding is a single result; proc is set for findings that concern
+// a running process.
+type finding struct {
+	proc    bool
+	message string
+	extra   []string
+}
+
+func (s *systemScanner) Name() string { return "LD-Preload" }
+
+func (s *systemScanner) Init() error { return nil }
+
+func (s *systemScanner) Scan() error {
+	var findings []finding
+	findings = append(findings, s.checkPreloadFile()...)
+	findings = append(findings, s.checkEnviron()...)
+	findings = append(findings, s.checkLdSoConf()...)
+	for _, f := range findings {
+		if f.proc {
+			report.AddProcInfo("ld_preload", f.message, f.extra...)
+		} else {
+			report.AddSystemInfo("ld_preload", f.message, f.extra...)
+		}
+	}
+	return nil
+}
+
+func (s *systemScanner) path(p string) string { return filepath.Join(s.root, p) }
+
+// readList returns the non-empty, non-comment lines of a file, split
+// into whitespace-separated words.
+func (s *systemScanner) readList(p string) ([]string, error) {
+	f, err := os.Open(s.path(p))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	return words, scanner.Err()
+}
+
+func (s *systemScanner) hashFile(p string) (string, error) {
+	f, err := os.Open(s.path(p))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+const preloadFile = "/etc/ld.so.preload"
+
+// checkPreloadFile reports a non-empty /etc/ld.so.preload and every
+// library listed in it.
+func (s *systemScanner) checkPreloadFile() []finding {
+	libs, err := s.readList(preloadFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Could not read %s: %v", preloadFile, err)
+		}
+		return nil
+	}
+	if len(libs) == 0 {
+		return nil
+	}
+	findings := []finding{{
+		message: fmt.Sprintf("%s is not empty: %s", preloadFile, strings.Join(libs, " ")),
+		extra: []string{
+			"rule", "ld_so_preload",
+			"Filepath", preloadFile,
+			"libraries", strings.Join(libs, "|"),
+		},
+	}}
+	for _, lib := range libs {
+		hash, err := s.hashFile(lib)
+		status := "present"
+		if os.IsNotExist(err) {
+			status = "missing"
+		} else if err != nil {
+			status = err.Error()
+		}
+		findings = append(findings, finding{
+			message: fmt.Sprintf("Library %s is preloaded via %s", lib, preloadFile),
+			extra: []string{
+				"rule", "ld_so_preload_library",
+				"Filepath", lib,
+				"Filehash256", hash,
+				"status", status,
+			},
+		})
+	}
+	return findings
+}
+
+// checkEnviron reports processes that have been started with
+// LD_PRELOAD or LD_LIBRARY_PATH set.
+func (s *systemScanner) checkEnviron() []finding {
+	pids, err := s.proc.Pids()
+	if err != nil {
+		log.Errorf("Could not enumerate processes: %v", err)
+		return nil
+	}
+	self := os.Getpid()
+	var findings []finding
+	for _, pid := range pids {
+		if pid == self {
+			continue
+		}
+		p := s.proc.Proc(pid)
+		environ, err := p.Environ()
+		if err != nil {
+			log.Debugf("Could not read environment of pid %d: %v", pid, err)
+			continue
+		}
+		for _, v := range []struct{ name, rule string }{
+			{"LD_PRELOAD", "ld_preload_env"},
+			{"LD_LIBRARY_PATH", "ld_library_path_env"},
+		} {
+			value, ok := procfs.Getenv(environ, v.name)
+			if !ok || value == "" {
+				continue
+			}
+			comm, _ := p.Comm()
+			exe, _ := p.Exe()
+			args, _ := p.Cmdline()
+			var ppid string
+			if st, err := p.Stat(); err == nil {
+				ppid = strconv.Itoa(st.PPID)
+			}
+			uid, _ := p.UID()
+			findings = append(findings, finding{
+				proc:    true,
+				message: fmt.Sprintf("Process %s[%d] was started with %s=%s", comm, pid, v.name, value),
+				extra: []string{
+					"rule", v.rule,
+					"PID", strconv.Itoa(pid),
+					"PPID", ppid,
+					"Process", comm,
+					"pathexe", exe,
+					"cmdline", strings.Join(args, " "),
+					"uid", uid,
+					"variable", v.name,
+					"value", value,
+				},
+			})
+		}
+	}
+	return findings
+}
+
+// ldSoConfDirs returns the library directories configured in
+// /etc/ld.so.conf, following include directives.
+func (s *systemScanner) ldSoConfDirs(conf string, seen map[string]bool) (dirs []string) {
+	if seen[conf] {
+		return nil
+	}
+	seen[conf] = true
+	words, err := s.readList(conf)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Could not read %s: %v", conf, err)
+		}
+		return nil
+	}
+	for i := 0; i < len(words); i++ {
+		if words[i] == "include" && i+1 < len(words) {
+			i++
+			pattern := words[i]
+			if !path.IsAbs(pattern) {
+				pattern = path.Join(path.Dir(conf), pattern)
+			}
+			matches, _ := filepath.Glob(s.path(pattern))
+			for _, m := range matches {
+				rel, _ := filepath.Rel(s.root, m)
+				dirs = append(dirs, s.ldSoConfDirs("/"+filepath.ToSlash(rel), seen)...)
+			}
+			continue
+		}
+		// Old-style entries may specify a library type,
+		// e.g. "/usr/lib=libc5".
+		dir := words[i]
+		if j := strings.IndexByte(dir, '='); j > 0 {
+			dir = dir[:j]
+		}
+		if dir = strings.TrimRight(dir, ":,"); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// writableDir reports whether dir is world-writable. If dir does not
+// exist, its nearest existing parent is checked instead, since any
+// user could create dir there. The directory that was found to be
+// writable is returned.
+func (s *systemScanner) writableDir(dir string) (string, bool) {
+	for d := path.Clean(dir); ; d = path.Dir(d) {
+		if fi, err := os.Stat(s.path(d)); err == nil {
+			if fi.Mode().Perm()&0002 == 0 {
+				return "", false
+			}
+			return d, true
+		}
+		if d == "/" {
+			return "", false
+		}
+	}
+}
+
+// checkLdSoConf reports library directories configured in ld.so.conf
+// that are world-writable.
+func (s *systemScanner) checkLdSoConf() []finding {
+	var findings []finding
+	for _, dir := range s.ldSoConfDirs("/etc/ld.so.conf", make(map[string]bool)) {
+		if !path.IsAbs(dir) {
+			continue
+		}
+		writable, ok := s.writableDir(dir)
+		if !ok {
+			continue
+		}
+		findings = append(findings, finding{
+			message: fmt.Sprintf("Library directory %s configured in ld.so.conf is world-writable (%s)", dir, writable),
+			extra: []string{
+				"rule", "ld_so_conf_writable_dir",
+				"Filepath", dir,
+				"writable_dir", writable,
+			},
+		})
+	}
+	return findings
+}
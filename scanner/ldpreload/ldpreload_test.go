@@ -0,0 +1,80 @@
+// +build linux
+
+package ldpreload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+// field returns the value of a field of a finding.
+func field(f finding, key string) string {
+	for i := 0; i+1 < len(f.extra); i += 2 {
+		if f.extra[i] == key {
+			return f.extra[i+1]
+		}
+	}
+	return ""
+}
+
+// rules returns the rule and the file or process of each finding.
+func rules(findings []finding) (r []string) {
+	for _, f := range findings {
+		subject := field(f, "Filepath")
+		if f.proc {
+			subject = field(f, "PID")
+		}
+		r = append(r, field(f, "rule")+" "+subject)
+	}
+	return
+}
+
+func TestScan(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	tr.Write("etc/ld.so.preload", "# rootkit\n/lib/libevil.so /lib/gone.so\n")
+	tr.Write("lib/libevil.so", "")
+	tr.Write("etc/ld.so.conf", "include ld.so.conf.d/*.conf\n/usr/local/lib\n")
+	tr.Write("etc/ld.so.conf.d/x.conf", "/opt/drop\n/opt/missing/lib\n")
+	tr.Mkdir("usr/local/lib", 0755)
+	tr.Mkdir("opt", 0777)
+	tr.Mkdir("opt/drop", 0777)
+	tr.Write("proc/100/environ", "HOME=/\x00LD_PRELOAD=/tmp/x.so\x00")
+	tr.Write("proc/100/comm", "sshd\n")
+	tr.Write("proc/200/environ", "HOME=/\x00LD_LIBRARY_PATH=\x00")
+	tr.Write("proc/300/environ", "LD_LIBRARY_PATH=/dev/shm\x00")
+
+	s := &systemScanner{root: tr.Root, proc: procfs.FS(tr.Path("proc"))}
+
+	if got, expected := rules(s.checkPreloadFile()), []string{
+		"ld_so_preload /etc/ld.so.preload",
+		"ld_so_preload_library /lib/libevil.so",
+		"ld_so_preload_library /lib/gone.so",
+	}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("preload: got %q, expected %q", got, expected)
+	}
+	f := s.checkPreloadFile()
+	if hash := field(f[1], "Filehash256"); hash != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("unexpected hash %s", hash)
+	}
+	if status := field(f[2], "status"); status != "missing" {
+		t.Errorf("unexpected status %s", status)
+	}
+
+	if got, expected := rules(s.checkEnviron()), []string{
+		"ld_preload_env 100",
+		"ld_library_path_env 300",
+	}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("environ: got %q, expected %q", got, expected)
+	}
+
+	if got, expected := rules(s.checkLdSoConf()), []string{
+		"ld_so_conf_writable_dir /opt/drop",
+		"ld_so_conf_writable_dir /opt/missing/lib",
+	}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("ld.so.conf: got %q, expected %q", got, expected)
+	}
+}
@@ -6,7 +6,10 @@ import (
 
 	"github.com/spf13/afero"
 	// Pull in scan modules
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 )
 
 // SystemScanner scans are run right after Spyre initialization. They
@@ -43,6 +46,27 @@ type EvtxScanner interface {
 	ScanEvtx(string, []byte) error
 }
 
+// Fingerprinter can be implemented by FileScanner modules whose
+// results depend on loaded content such as rule files. The
+// fingerprint must change whenever that content changes.
+type Fingerprinter interface {
+	Fingerprint() string
+}
+
+// Finisher can be implemented by FileScanner modules that need to act
+// after all files have been passed to ScanFile, e.g. to report files
+// that have not been seen.
+type Finisher interface {
+	Finish() error
+}
+
+// Uncacheable can be implemented by FileScanner modules that need to
+// see certain files in every scan, even if they are unchanged since
+// an earlier scan.
+type Uncacheable interface {
+	MustScan(path string) bool
+}
+
 var (
 	systemScanners []SystemScanner
 	fileScanners   []FileScanner
@@ -120,9 +144,23 @@ func InitModules() error {
 	return nil
 }
 
+// FileScanFingerprint returns a value that identifies the active file
+// scan modules and their rules. Results of a file scan can be reused
+// as long as the fingerprint stays the same.
+func FileScanFingerprint() string {
+	h := sha256.New()
+	for _, s := range fileScanners {
+		fmt.Fprintf(h, "%s\n", s.Name())
+		if fp, ok := s.(Fingerprinter); ok {
+			fmt.Fprintf(h, "%s\n", fp.Fingerprint())
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 func ScanSystem() (err error) {
 	for _, s := range systemScanners {
-		if e := s.Scan(); err == nil && e != nil {
+		if e := track(s.Name(), func() error { return s.Scan() }); err == nil && e != nil {
 			err = e
 		}
 	}
@@ -131,16 +169,39 @@ func ScanSystem() (err error) {
 
 func ScanFile(f afero.File) (err error) {
 	for _, s := range fileScanners {
-		if e := s.ScanFile(f); err == nil && e != nil {
+		if e := track(s.Name(), func() error { return s.ScanFile(f) }); err == nil && e != nil {
 			err = e
 		}
 	}
 	return
 }
 
+// MustScan reports whether a file must be passed to ScanFile even if
+// it has not changed since an earlier scan.
+func MustScan(path string) bool {
+	for _, s := range fileScanners {
+		if u, ok := s.(Uncacheable); ok && u.MustScan(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// FinishFileScan is called after the file walk has been completed.
+func FinishFileScan() (err error) {
+	for _, s := range fileScanners {
+		if f, ok := s.(Finisher); ok {
+			if e := f.Finish(); err == nil && e != nil {
+				err = e
+			}
+		}
+	}
+	return
+}
+
 func ScanProc(proc int32) (err error) {
 	for _, s := range procScanners {
-		if e := s.ScanProc(proc); err == nil && e != nil {
+		if e := track(s.Name(), func() error { return s.ScanProc(proc) }); err == nil && e != nil {
 			err = e
 		}
 	}
@@ -149,7 +210,7 @@ func ScanProc(proc int32) (err error) {
 
 func ScanEvtx(evt string, jsonval []byte) (err error) {
 	for _, s := range evtxScanners {
-		if e := s.ScanEvtx(evt, jsonval); err == nil && e != nil {
+		if e := track(s.Name(), func() error { return s.ScanEvtx(evt, jsonval) }); err == nil && e != nil {
 			err = e
 		}
 	}
@@ -0,0 +1,134 @@
+// +build linux amd64
+
+package netscan
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ipMatcher matches an address against a CIDR block, a single IP
+// address, or, for values that are neither, the address's string
+// representation.
+type ipMatcher struct {
+	net   *net.IPNet
+	ip    net.IP
+	value string
+}
+
+func parseIPMatcher(value string) (ipMatcher, error) {
+	if strings.Contains(value, "/") {
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return ipMatcher{}, err
+		}
+		return ipMatcher{net: n}, nil
+	}
+	return ipMatcher{ip: net.ParseIP(value), value: value}, nil
+}
+
+func (m ipMatcher) match(ip net.IP) bool {
+	switch {
+	case m.net != nil:
+		return m.net.Contains(ip)
+	case m.ip != nil:
+		return m.ip.Equal(ip)
+	default:
+		return strings.EqualFold(m.value, ip.String())
+	}
+}
+
+type ipList []ipMatcher
+
+func parseIPList(values []string) (l ipList, err error) {
+	for _, v := range values {
+		m, err := parseIPMatcher(v)
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+	}
+	return l, nil
+}
+
+func (l ipList) contains(ip net.IP) bool {
+	for _, m := range l {
+		if m.match(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// portSpec is a port number or a range of ports such as "8000-9000".
+// Both JSON numbers and strings are accepted.
+type portSpec string
+
+func (p *portSpec) UnmarshalJSON(buf []byte) error {
+	var n int
+	if err := json.Unmarshal(buf, &n); err == nil {
+		*p = portSpec(strconv.Itoa(n))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(buf, &s); err != nil {
+		return errors.New("port must be a number or a string")
+	}
+	*p = portSpec(s)
+	return nil
+}
+
+// portRange is an inclusive range of ports.
+type portRange struct{ lo, hi int }
+
+func parsePort(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 || n > 65535 {
+		return 0, errors.New("invalid port: " + s)
+	}
+	return n, nil
+}
+
+func (p portSpec) parse() (r portRange, err error) {
+	s := string(p)
+	if i := strings.IndexByte(s, '-'); i > 0 {
+		if r.lo, err = parsePort(s[:i]); err != nil {
+			return
+		}
+		if r.hi, err = parsePort(s[i+1:]); err != nil {
+			return
+		}
+		if r.lo > r.hi {
+			err = errors.New("invalid port range: " + s)
+		}
+		return
+	}
+	r.lo, err = parsePort(s)
+	r.hi = r.lo
+	return
+}
+
+type portList []portRange
+
+func parsePortList(specs []portSpec) (l portList, err error) {
+	for _, p := range specs {
+		r, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		l = append(l, r)
+	}
+	return l, nil
+}
+
+func (l portList) contains(port int) bool {
+	for _, r := range l {
+		if r.lo <= port && port <= r.hi {
+			return true
+		}
+	}
+	return false
+}
@@ -4,37 +4,66 @@ package netscan
 
 import (
 	"fmt"
+	"net"
+	"regexp"
 	"strings"
 
 	"github.com/cakturk/go-netstat/netstat"
+	"github.com/spyre-project/spyre/baseline"
 	"github.com/spyre-project/spyre/config"
 	"github.com/spyre-project/spyre/log"
 	"github.com/spyre-project/spyre/report"
 	"github.com/spyre-project/spyre/scanner"
 )
 
-func init() { scanner.RegisterSystemScanner(&systemScanner{}) }
+func init() { scanner.RegisterSystemScanner(&systemScanner{lookup: lookupOwner}) }
 
 type systemScanner struct {
 	iocs []eventIOC
+	// lookup returns the process with the given PID that owns a
+	// socket, or nil.
+	lookup func(pid int) *owner
 }
 
 type eventIOC struct {
-	Dip         []string `json:"dip"`
-	Sip         []string `json:"sip"`
-	Sport       []int    `json:"sport"`
-	Dport       []int    `json:"dport"`
-	Pname       []string `json:"pname"`
-	NPname      []string `json:"notpname"`
-	State       []string `json:"state"`
-	Proto       string   `json:"proto"`
-	Description string   `json:"description"`
+	Dip         []string   `json:"dip"`
+	Sip         []string   `json:"sip"`
+	NDip        []string   `json:"notdip"`
+	NSip        []string   `json:"notsip"`
+	Sport       []portSpec `json:"sport"`
+	Dport       []portSpec `json:"dport"`
+	NSport      []portSpec `json:"notsport"`
+	NDport      []portSpec `json:"notdport"`
+	Pname       []string   `json:"pname"`
+	NPname      []string   `json:"notpname"`
+	Pathexe     string     `json:"pathexe"`
+	Cmdline     string     `json:"cmdline"`
+	State       []string   `json:"state"`
+	Proto       string     `json:"proto"`
+	Description string     `json:"description"`
+	pathexe     *regexp.Regexp
+	cmdline     *regexp.Regexp
+	dip, sip    ipList
+	ndip, nsip  ipList
+	sport       portList
+	dport       portList
+	nsport      portList
+	ndport      portList
 }
 
 type iocFile struct {
 	Keys []eventIOC `json:"netstat"`
 }
 
+// owner describes the process that owns a socket.
+type owner struct {
+	Key      string // process_key, see procfs.Key
+	Exe      string
+	Cmdline  string
+	Username string
+	Hash     string // SHA256 of the executable
+}
+
 func (s *systemScanner) Name() string { return "Netstat" }
 
 func (s *systemScanner) Init() error {
@@ -48,22 +77,54 @@ func (s *systemScanner) Init() error {
 			log.Error(err.Error())
 		}
 		for _, ioc := range current.Keys {
+			if err := ioc.compile(); err != nil {
+				log.Noticef("Error in netstat rule %s: %s", ioc.Description, err)
+				continue
+			}
 			s.iocs = append(s.iocs, ioc)
 		}
 	}
 	return nil
 }
 
-func intInSlice(a int, list []int) bool {
-	if len(list) == 0 {
-		return true
+// compile parses the address and port lists and prepares the regular
+// expressions of a rule.
+func (ioc *eventIOC) compile() (err error) {
+	for _, v := range []struct {
+		values []string
+		list   *ipList
+	}{
+		{ioc.Dip, &ioc.dip},
+		{ioc.Sip, &ioc.sip},
+		{ioc.NDip, &ioc.ndip},
+		{ioc.NSip, &ioc.nsip},
+	} {
+		if *v.list, err = parseIPList(v.values); err != nil {
+			return
+		}
 	}
-	for _, b := range list {
-		if b == a {
-			return true
+	for _, v := range []struct {
+		specs []portSpec
+		list  *portList
+	}{
+		{ioc.Sport, &ioc.sport},
+		{ioc.Dport, &ioc.dport},
+		{ioc.NSport, &ioc.nsport},
+		{ioc.NDport, &ioc.ndport},
+	} {
+		if *v.list, err = parsePortList(v.specs); err != nil {
+			return
 		}
 	}
-	return false
+	if ioc.Pathexe != "" {
+		if ioc.pathexe, err = regexp.Compile(ioc.Pathexe); err != nil {
+			return
+		}
+	}
+	if ioc.Cmdline != "" {
+		ioc.cmdline, err = regexp.Compile(ioc.Cmdline)
+	}
+	return
 }
 
 func stringInSlice(a string, list []string) bool {
@@ -90,102 +151,187 @@ func nstringInSlice(a string, list []string) bool {
 	return false
 }
 
-func (s *systemScanner) Scan() error {
-	tsocks, err := netstat.TCPSocks(netstat.NoopFilter)
-	if err != nil {
-		log.Debugf("Error to get TCP socks : %s", err)
-	}
-	usocks, err := netstat.UDPSocks(netstat.NoopFilter)
-	if err != nil {
-		log.Debugf("Error to get UDP socks : %s", err)
-	}
-	for _, ioc := range s.iocs {
-		//netCheck(ioc.Dip, ioc.Sip, ioc.Sport, ioc.Dport, ioc.Pname, ioc.State)
-		for _, e := range tsocks {
-			//fmt.Printf("%v\n", e)
-			pid := "unknown"
-			proc_name := "unknown"
-			port_src := fmt.Sprintf("%d", e.LocalAddr.Port)
-			port_dst := fmt.Sprintf("%d", e.RemoteAddr.Port)
-			uid := fmt.Sprintf("%d", e.UID)
-
-			if !(strings.EqualFold(ioc.Proto, "tcp") || ioc.Proto == "*" || ioc.Proto == "") {
+// matchAddr checks an address against a list of addresses that must
+// match (if not empty) and a list of addresses that must not match.
+func matchAddr(ip net.IP, port int, ips, nips ipList, ports, nports portList) bool {
+	if len(ips) > 0 && !ips.contains(ip) {
+		return false
+	}
+	if nips.contains(ip) {
+		return false
+	}
+	if len(ports) > 0 && !ports.contains(port) {
+		return false
+	}
+	return !nports.contains(port)
+}
+
+// matchProto reports whether a rule's protocol matches a socket table.
+// "tcp" and "udp" match both IPv4 and IPv6 sockets, "tcp6" and "udp6"
+// only IPv6 sockets.
+func (ioc *eventIOC) matchProto(proto string) bool {
+	return ioc.Proto == "" || ioc.Proto == "*" ||
+		strings.EqualFold(ioc.Proto, proto) ||
+		strings.EqualFold(ioc.Proto, strings.TrimSuffix(proto, "6"))
+}
+
+// matchOwner checks the pathexe and cmdline regular expressions of a
+// rule. Sockets whose owner is unknown do not match if any of them is
+// set.
+func (ioc *eventIOC) matchOwner(o *owner) bool {
+	if ioc.pathexe == nil && ioc.cmdline == nil {
+		return true
+	}
+	if o == nil {
+		return false
+	}
+	if ioc.pathexe != nil && !ioc.pathexe.MatchString(o.Exe) {
+		return false
+	}
+	if ioc.cmdline != nil && !ioc.cmdline.MatchString(o.Cmdline) {
+		return false
+	}
+	return true
+}
+
+// sockTable is a list of sockets for one protocol, e.g. "tcp6".
+type sockTable struct {
+	proto string
+	socks []netstat.SockTabEntry
+}
+
+// recordListening adds listening TCP sockets and unconnected UDP
+// sockets to the baseline.
+func recordListening(tables []sockTable) {
+	if !baseline.Enabled() {
+		return
+	}
+	baseline.Collecting("listen")
+	for _, t := range tables {
+		for _, e := range t.socks {
+			if strings.HasPrefix(t.proto, "tcp") && e.State != netstat.Listen ||
+				strings.HasPrefix(t.proto, "udp") && e.RemoteAddr.Port != 0 {
 				continue
 			}
+			proc := ""
 			if e.Process != nil {
-				proc_name = fmt.Sprintf("%s", e.Process.Name)
-				pid = fmt.Sprintf("%d", e.Process.Pid)
-				if !(stringInSlice(e.Process.Name, ioc.Pname)) {
-				  continue
-			  }
-				if nstringInSlice(e.Process.Name, ioc.NPname) {
-					continue
-				}
-			}
-			dip := e.RemoteAddr.IP.String()
-			if !(stringInSlice(dip, ioc.Dip)) {
-				continue
-			}
-			sip := e.LocalAddr.IP.String()
-			if !(stringInSlice(sip, ioc.Sip)) {
-				continue
-			}
-			if !(intInSlice(int(e.LocalAddr.Port), ioc.Sport)) {
-				continue
-			}
-			if !(intInSlice(int(e.RemoteAddr.Port), ioc.Dport)) {
-				continue
+				proc = e.Process.Name
 			}
-			state := fmt.Sprintf("%s", e.State)
-			if !(stringInSlice(state, ioc.State)) {
-				continue
-			}
-			message := fmt.Sprintf("Found netstat rule: %s on TCP %v",ioc.Description, e)
-			report.AddNetstatInfo("ioc_on_netstat", message,
-				"rule", ioc.Description, "State", state, "ip_src", sip, "ip_dst", dip, "uid", uid, "PID", pid, "Process", proc_name, "port_dst", port_dst, "port_src", port_src, "proto", "TCP")
+			baseline.Record("listen", fmt.Sprintf("%s %s", t.proto, e.LocalAddr), proc)
 		}
-		for _, e := range usocks {
-			pid := "unknown"
-			proc_name := "unknown"
-			port_src := fmt.Sprintf("%d", e.LocalAddr.Port)
-			port_dst := fmt.Sprintf("%d", e.RemoteAddr.Port)
-			uid := fmt.Sprintf("%d", e.UID)
-			//fmt.Printf("%v\n", e)
-			if !(strings.EqualFold(ioc.Proto, "udp") || ioc.Proto == "*" || ioc.Proto == "") {
-				continue
-			}
-			if e.Process != nil {
-				proc_name = fmt.Sprintf("%s", e.Process.Name)
-				pid = fmt.Sprintf("%d", e.Process.Pid)
-				if !(stringInSlice(e.Process.Name, ioc.Pname)) {
-				  continue
-			  }
-				if nstringInSlice(e.Process.Name, ioc.NPname) {
-					continue
+	}
+}
+
+// finding is a socket that matched a rule.
+type finding struct {
+	message string
+	extra   []string
+}
+
+// check returns a finding if a socket matches a rule. Owners are
+// looked up only once the cheaper checks have passed, and at most
+// once per process.
+func (s *systemScanner) check(ioc *eventIOC, proto string, e netstat.SockTabEntry, owners map[int]*owner) (f finding, ok bool) {
+	pid := "unknown"
+	proc_name := "unknown"
+	port_src := fmt.Sprintf("%d", e.LocalAddr.Port)
+	port_dst := fmt.Sprintf("%d", e.RemoteAddr.Port)
+	uid := fmt.Sprintf("%d", e.UID)
+
+	if !ioc.matchProto(proto) {
+		return f, false
+	}
+	if e.Process != nil {
+		proc_name = fmt.Sprintf("%s", e.Process.Name)
+		pid = fmt.Sprintf("%d", e.Process.Pid)
+		if !(stringInSlice(e.Process.Name, ioc.Pname)) {
+			return f, false
+		}
+		if nstringInSlice(e.Process.Name, ioc.NPname) {
+			return f, false
+		}
+	}
+	if !matchAddr(e.RemoteAddr.IP, int(e.RemoteAddr.Port), ioc.dip, ioc.ndip, ioc.dport, ioc.ndport) {
+		return f, false
+	}
+	if !matchAddr(e.LocalAddr.IP, int(e.LocalAddr.Port), ioc.sip, ioc.nsip, ioc.sport, ioc.nsport) {
+		return f, false
+	}
+	dip := e.RemoteAddr.IP.String()
+	sip := e.LocalAddr.IP.String()
+	state := fmt.Sprintf("%s", e.State)
+	if !(stringInSlice(state, ioc.State)) {
+		return f, false
+	}
+	var o *owner
+	if e.Process != nil {
+		var cached bool
+		if o, cached = owners[e.Process.Pid]; !cached {
+			o = s.lookup(e.Process.Pid)
+			owners[e.Process.Pid] = o
+		}
+	}
+	if !ioc.matchOwner(o) {
+		return f, false
+	}
+	message := fmt.Sprintf("Found netstat rule: %s on %s %v", ioc.Description, strings.ToUpper(proto), e)
+	extra := []string{"rule", ioc.Description, "State", state, "ip_src", sip, "ip_dst", dip, "uid", uid, "PID", pid, "Process", proc_name, "port_dst", port_dst, "port_src", port_src, "proto", strings.ToUpper(proto)}
+	if o != nil {
+		extra = append(extra,
+			"process_key", o.Key,
+			"pathexe", o.Exe,
+			"cmdline", o.Cmdline,
+			"username", o.Username,
+			"Filehash256", o.Hash,
+		)
+	}
+	return finding{message, extra}, true
+}
+
+// scan returns the sockets that match any rule.
+func (s *systemScanner) scan(tables []sockTable) (findings []finding) {
+	owners := make(map[int]*owner)
+	for i := range s.iocs {
+		for _, t := range tables {
+			for _, e := range t.socks {
+				if f, ok := s.check(&s.iocs[i], t.proto, e, owners); ok {
+					findings = append(findings, f)
 				}
 			}
-			dip := e.RemoteAddr.IP.String()
-			if !(stringInSlice(dip, ioc.Dip)) {
-				continue
-			}
-			sip := e.LocalAddr.IP.String()
-			if !(stringInSlice(sip, ioc.Sip)) {
-				continue
-			}
-			if !(intInSlice(int(e.LocalAddr.Port), ioc.Sport)) {
-				continue
-			}
-			if !(intInSlice(int(e.RemoteAddr.Port), ioc.Dport)) {
-				continue
-			}
-			state := fmt.Sprintf("%s", e.State)
-			if !(stringInSlice(state, ioc.State)) {
-				continue
+		}
+	}
+	return
+}
+
+func (s *systemScanner) Scan() error {
+	var tables []sockTable
+	complete := true
+	for _, t := range []struct {
+		proto string
+		socks func(netstat.AcceptFn) ([]netstat.SockTabEntry, error)
+	}{
+		{"tcp", netstat.TCPSocks},
+		{"tcp6", netstat.TCP6Socks},
+		{"udp", netstat.UDPSocks},
+		{"udp6", netstat.UDP6Socks},
+	} {
+		socks, err := t.socks(netstat.NoopFilter)
+		if err != nil {
+			log.Debugf("Error to get %s socks : %s", strings.ToUpper(t.proto), err)
+			// IPv6 may be disabled; the baseline is only
+			// skipped if IPv4 sockets cannot be listed.
+			if !strings.HasSuffix(t.proto, "6") {
+				complete = false
 			}
-			message := fmt.Sprintf("Found netstat rule: %s on UDP %v",ioc.Description, e)
-			report.AddNetstatInfo("ioc_on_netstat", message,
-				"rule", ioc.Description, "State", state, "ip_src", sip, "ip_dst", dip, "uid", uid, "PID", pid, "Process", proc_name, "port_dst", port_dst, "port_src", port_src, "proto", "UDP")
+			continue
 		}
+		tables = append(tables, sockTable{t.proto, socks})
+	}
+	if complete {
+		recordListening(tables)
+	}
+	for _, f := range s.scan(tables) {
+		report.AddNetstatInfo("ioc_on_netstat", f.message, f.extra...)
 	}
 	return nil
 }
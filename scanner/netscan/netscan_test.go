@@ -0,0 +1,204 @@
+// +build linux amd64
+
+package netscan
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/cakturk/go-netstat/netstat"
+)
+
+func TestCheckOwner(t *testing.T) {
+	owners := map[int]*owner{
+		100: {Exe: "/usr/bin/bash", Cmdline: "bash -i", Username: "www-data", Hash: "abc"},
+		200: {Exe: "/usr/sbin/sshd", Cmdline: "sshd: root@pts/0", Username: "root"},
+	}
+	lookups := 0
+	s := &systemScanner{lookup: func(pid int) *owner { lookups++; return owners[pid] }}
+	sock := func(pid int, name string) netstat.SockTabEntry {
+		e := netstat.SockTabEntry{
+			LocalAddr:  &netstat.SockAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000},
+			RemoteAddr: &netstat.SockAddr{IP: net.ParseIP("203.0.113.5"), Port: 4444},
+			State:      netstat.Established,
+		}
+		if pid != 0 {
+			e.Process = &netstat.Process{Pid: pid, Name: name}
+		}
+		return e
+	}
+	var ioc eventIOC
+	if err := json.Unmarshal([]byte(`{"pathexe": "/(ba|da)?sh$", "state": ["ESTABLISHED"], "proto": "tcp", "description": "shell with outbound connection"}`), &ioc); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioc.compile(); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]*owner)
+	f, ok := s.check(&ioc, "tcp", sock(100, "bash"), seen)
+	if !ok {
+		t.Fatal("bash socket did not match")
+	}
+	fields := make(map[string]string)
+	for i := 0; i+1 < len(f.extra); i += 2 {
+		fields[f.extra[i]] = f.extra[i+1]
+	}
+	for k, v := range map[string]string{
+		"PID": "100", "pathexe": "/usr/bin/bash", "cmdline": "bash -i",
+		"username": "www-data", "Filehash256": "abc", "ip_dst": "203.0.113.5",
+	} {
+		if fields[k] != v {
+			t.Errorf("%s: got %q, expected %q", k, fields[k], v)
+		}
+	}
+	for _, c := range []struct {
+		proto string
+		e     netstat.SockTabEntry
+	}{
+		{"tcp", sock(200, "sshd")},
+		{"tcp", sock(0, "")},
+		{"udp", sock(100, "bash")},
+	} {
+		if _, ok := s.check(&ioc, c.proto, c.e, seen); ok {
+			t.Errorf("unexpected match: %s %v", c.proto, c.e)
+		}
+	}
+
+	// Owners are not looked up for sockets that fail the address
+	// or state checks.
+	lookups = 0
+	listening := sock(300, "nginx")
+	listening.State = netstat.Listen
+	if _, ok := s.check(&ioc, "tcp", listening, make(map[int]*owner)); ok || lookups != 0 {
+		t.Errorf("listening socket: match %v, %d lookups", ok, lookups)
+	}
+
+	ioc = eventIOC{Cmdline: "(", Description: "broken"}
+	if err := ioc.compile(); err == nil {
+		t.Error("invalid cmdline regexp was accepted")
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	var ioc eventIOC
+	if err := json.Unmarshal([]byte(`{"dip": ["10.0.0.0/8", "2001:db8::1"], "dport": [80, "8000-9000"], "notdport": ["8080"]}`), &ioc); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioc.compile(); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		ip   string
+		port int
+		want bool
+	}{
+		{"10.1.2.3", 80, true},
+		{"10.1.2.3", 8500, true},
+		{"10.1.2.3", 8080, false},
+		{"10.1.2.3", 9001, false},
+		{"11.0.0.1", 80, false},
+		{"2001:db8::1", 9000, true},
+		{"2001:db8::2", 9000, false},
+	} {
+		if got := matchAddr(net.ParseIP(c.ip), c.port, ioc.dip, ioc.ndip, ioc.dport, ioc.ndport); got != c.want {
+			t.Errorf("%s:%d: got %v, expected %v", c.ip, c.port, got, c.want)
+		}
+	}
+	for _, rule := range []string{
+		`{"dip": ["10.0.0.0/33"]}`,
+		`{"dport": ["9000-8000"]}`,
+		`{"sport": ["http"]}`,
+		`{"notsport": [70000]}`,
+	} {
+		var ioc eventIOC
+		if err := json.Unmarshal([]byte(rule), &ioc); err != nil {
+			t.Errorf("%s: %v", rule, err)
+			continue
+		}
+		if err := ioc.compile(); err == nil {
+			t.Errorf("%s: invalid rule was accepted", rule)
+		}
+	}
+}
+
+func TestScanTables(t *testing.T) {
+	entry := func(local, remote string, state netstat.SkState, pid int, name string) netstat.SockTabEntry {
+		addr := func(s string) *netstat.SockAddr {
+			host, port, err := net.SplitHostPort(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p, _ := strconv.Atoi(port)
+			return &netstat.SockAddr{IP: net.ParseIP(host), Port: uint16(p)}
+		}
+		e := netstat.SockTabEntry{LocalAddr: addr(local), RemoteAddr: addr(remote), State: state}
+		if pid != 0 {
+			e.Process = &netstat.Process{Pid: pid, Name: name}
+		}
+		return e
+	}
+	tables := []sockTable{
+		{"tcp", []netstat.SockTabEntry{
+			entry("0.0.0.0:22", "0.0.0.0:0", netstat.Listen, 1, "sshd"),
+			entry("192.168.1.10:51000", "198.51.100.7:8443", netstat.Established, 10, "curl"),
+			entry("192.168.1.10:51001", "192.168.1.1:8443", netstat.Established, 11, "curl"),
+			entry("192.168.1.10:51002", "198.51.100.7:443", netstat.Established, 12, "firefox"),
+		}},
+		{"tcp6", []netstat.SockTabEntry{
+			entry("[::]:22", "[::]:0", netstat.Listen, 1, "sshd"),
+			entry("[::]:8080", "[::]:0", netstat.Listen, 2, "python3"),
+			entry("[2001:db8::10]:52000", "[2001:db8:bad::5]:8000", netstat.Established, 20, "nc"),
+			entry("[::ffff:192.168.1.10]:52001", "[::ffff:203.0.113.9]:9000", netstat.Established, 21, "nc"),
+		}},
+		{"udp", []netstat.SockTabEntry{
+			entry("0.0.0.0:5353", "0.0.0.0:0", netstat.Close, 30, "avahi"),
+			entry("192.168.1.10:40000", "203.0.113.9:53", netstat.Established, 31, "dnscat"),
+		}},
+		{"udp6", []netstat.SockTabEntry{
+			entry("[::]:5353", "[::]:0", netstat.Close, 30, "avahi"),
+		}},
+	}
+	s := &systemScanner{lookup: func(int) *owner { return nil }}
+	for _, rule := range []string{
+		// Outbound TCP to non-private addresses on high ports
+		`{"proto": "tcp", "state": ["ESTABLISHED"], "dport": ["8000-9999"],
+		  "notdip": ["10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"], "description": "high-port"}`,
+		// IPv6 only
+		`{"proto": "tcp6", "dip": ["2001:db8:bad::/48"], "description": "v6-net"}`,
+		// Listening sockets, except SSH
+		`{"state": ["LISTEN"], "notsport": [22], "description": "listen"}`,
+		// UDP to the documentation network, any protocol version
+		`{"proto": "udp", "dip": ["203.0.113.0/24"], "description": "udp-doc"}`,
+	} {
+		var ioc eventIOC
+		if err := json.Unmarshal([]byte(rule), &ioc); err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+		if err := ioc.compile(); err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+		s.iocs = append(s.iocs, ioc)
+	}
+	var got []string
+	for _, f := range s.scan(tables) {
+		fields := make(map[string]string)
+		for i := 0; i+1 < len(f.extra); i += 2 {
+			fields[f.extra[i]] = f.extra[i+1]
+		}
+		got = append(got, fields["rule"]+" "+fields["proto"]+" "+fields["PID"])
+	}
+	expected := []string{
+		"high-port TCP 10",
+		"high-port TCP6 20",
+		"high-port TCP6 21",
+		"v6-net TCP6 20",
+		"listen TCP6 2",
+		"udp-doc UDP 31",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,42 @@
+package netscan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+// lookupOwner collects information about the process that owns a
+// socket. go-netstat finds the process by looking for the socket's
+// inode among the /proc/<pid>/fd links.
+func lookupOwner(pid int) *owner {
+	p := procfs.Default.Proc(pid)
+	info := p.Info()
+	if info.Key == "" {
+		return nil
+	}
+	o := &owner{
+		Key:      info.Key,
+		Exe:      info.Exe,
+		Cmdline:  strings.Join(info.Cmdline, " "),
+		Username: info.UID,
+	}
+	if u, err := user.LookupId(info.UID); err == nil {
+		o.Username = u.Username
+	}
+	// The executable is read through /proc/<pid>/exe, which also
+	// works if it has been deleted.
+	if f, err := os.Open(p.Path("exe")); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			o.Hash = hex.EncodeToString(h.Sum(nil))
+		}
+		f.Close()
+	}
+	return o
+}
@@ -0,0 +1,33 @@
+// +build !linux,amd64
+
+package netscan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// lookupOwner collects information about the process that owns a
+// socket.
+func lookupOwner(pid int) *owner {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil
+	}
+	o := &owner{}
+	o.Exe, _ = p.Exe()
+	o.Cmdline, _ = p.Cmdline()
+	o.Username, _ = p.Username()
+	if f, err := os.Open(o.Exe); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			o.Hash = hex.EncodeToString(h.Sum(nil))
+		}
+		f.Close()
+	}
+	return o
+}
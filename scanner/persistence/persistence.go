@@ -0,0 +1,106 @@
+// +build linux
+
+package persistence
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/spyre-project/spyre/baseline"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+)
+
+func init() { scanner.RegisterSystemScanner(&systemScanner{root: "/"}) }
+
+type systemScanner struct {
+	root string
+	iocs []eventIOC
+}
+
+type eventIOC struct {
+	Value       string `json:"value"`
+	Type        int    `json:"type"`
+	Description string `json:"description"`
+	//type (same as autorun on Windows):
+	// 0 == line contains
+	// 1 == line not contains
+	// 2 == line contains regexp
+	// 3 == line not contains regexp
+	re *regexp.Regexp
+}
+
+type iocFile struct {
+	Keys []eventIOC `json:"persistence"`
+}
+
+func (s *systemScanner) Name() string { return "Persistence" }
+
+func (s *systemScanner) Init() error {
+	iocFiles := config.IocFiles
+	if len(iocFiles) == 0 {
+		iocFiles = []string{"ioc.json"}
+	}
+	for _, file := range iocFiles {
+		var current iocFile
+		if err := config.ReadIOCs(file, &current); err != nil {
+			log.Error(err.Error())
+		}
+		for _, ioc := range current.Keys {
+			if ioc.Type == 2 || ioc.Type == 3 {
+				re, err := regexp.Compile(ioc.Value)
+				if err != nil {
+					log.Noticef("Error regexp in persistence rule %s: %s", ioc.Description, err)
+					continue
+				}
+				ioc.re = re
+			}
+			s.iocs = append(s.iocs, ioc)
+		}
+	}
+	return nil
+}
+
+func (ioc *eventIOC) match(line string) bool {
+	switch ioc.Type {
+	case 0:
+		return strings.Contains(line, ioc.Value)
+	case 1:
+		return !strings.Contains(line, ioc.Value)
+	case 2:
+		return ioc.re.MatchString(line)
+	case 3:
+		return !ioc.re.MatchString(line)
+	}
+	return false
+}
+
+func (s *systemScanner) Scan() error {
+	entries := collect(s.root)
+	log.Infof("Found %d persistence entries", len(entries))
+	baseline.Collecting("autorun")
+	for _, e := range entries {
+		baseline.Record("autorun", e.Type+" "+e.Path+" "+e.Command,
+			fmt.Sprintf("owner=%s", e.Owner))
+		line := e.line()
+		for i := range s.iocs {
+			ioc := &s.iocs[i]
+			if !ioc.match(line) {
+				continue
+			}
+			message := fmt.Sprintf("Found persistence rule: %s on %s", ioc.Description, line)
+			report.AddAutorunInfo("ioc_on_persistence", message,
+				"rule", ioc.Description,
+				"autorun_type", e.Type,
+				"entry_location", e.location(),
+				"autorun_launch", e.Command,
+				"username", e.Owner,
+				"time_last_write", report.FormatTime(e.Mtime),
+			)
+		}
+	}
+	return nil
+}
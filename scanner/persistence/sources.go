@@ -0,0 +1,406 @@
+// +build linux
+
+package persistence
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spyre-project/spyre/platform"
+)
+
+// entry is a single persistence mechanism, normalized across sources.
+type entry struct {
+	Type    string // cron, anacron, systemd, sysv, rc.local, profile, xdg-autostart, udev
+	Path    string // file containing the entry
+	Line    int    // line number within Path, 0 if the whole file is the entry
+	Command string
+	Owner   string
+	Mtime   time.Time
+}
+
+func (e *entry) location() string {
+	if e.Line > 0 {
+		return e.Path + ":" + strconv.Itoa(e.Line)
+	}
+	return e.Path
+}
+
+// line returns the string that IOCs are matched against.
+func (e *entry) line() string {
+	return strings.Join([]string{e.Type, e.location(), e.Owner, e.Command}, ",")
+}
+
+var (
+	systemdDirs = []string{
+		"/etc/systemd/system", "/run/systemd/system",
+		"/usr/lib/systemd/system", "/lib/systemd/system",
+		"/etc/systemd/user", "/usr/lib/systemd/user",
+	}
+	udevDirs = []string{
+		"/etc/udev/rules.d", "/run/udev/rules.d",
+		"/usr/lib/udev/rules.d", "/lib/udev/rules.d",
+	}
+	systemProfiles = []string{
+		"/etc/profile", "/etc/profile.d/*.sh", "/etc/environment",
+		"/etc/bash.bashrc", "/etc/bashrc",
+		"/etc/zsh/zshenv", "/etc/zsh/zprofile", "/etc/zsh/zshrc", "/etc/zsh/zlogin",
+		"/etc/zshenv", "/etc/zprofile", "/etc/zshrc", "/etc/zlogin",
+	}
+	userProfiles = []string{
+		".profile", ".bash_profile", ".bash_login", ".bashrc", ".bash_logout",
+		".zshenv", ".zprofile", ".zshrc", ".zlogin",
+	}
+)
+
+type collector struct {
+	root    string
+	users   map[string]string // uid -> name
+	homes   map[string]string // home directory -> name
+	seen    map[string]bool   // resolved paths of files returned by glob
+	entries []entry
+}
+
+// collect enumerates persistence entries below root.
+func collect(root string) []entry {
+	c := &collector{
+		root:  root,
+		users: make(map[string]string),
+		homes: make(map[string]string),
+		seen:  make(map[string]bool),
+	}
+	c.readPasswd()
+	c.cron()
+	c.anacron()
+	c.systemd()
+	c.sysv()
+	c.rcLocal()
+	c.profiles()
+	c.xdgAutostart()
+	c.udev()
+	return c.entries
+}
+
+func (c *collector) path(p string) string { return filepath.Join(c.root, p) }
+
+func (c *collector) owner(fi os.FileInfo) string {
+	uid, _ := platform.FileOwner(fi)
+	if name, ok := c.users[uid]; ok {
+		return name
+	}
+	return uid
+}
+
+// glob returns the files matching pattern (relative to root), in
+// lexical order. Files that have already been returned through a
+// different path (e.g. /lib being a symlink to /usr/lib) are omitted.
+func (c *collector) glob(pattern string) []string {
+	matches, _ := filepath.Glob(c.path(pattern))
+	var files []string
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		if real, err := filepath.EvalSymlinks(m); err == nil {
+			if c.seen[real] {
+				continue
+			}
+			c.seen[real] = true
+		}
+		rel, _ := filepath.Rel(c.root, m)
+		files = append(files, "/"+filepath.ToSlash(rel))
+	}
+	sort.Strings(files)
+	return files
+}
+
+// readLines calls f for every line in p that is neither empty nor a
+// comment.
+func (c *collector) readLines(p string, f func(lineno int, line string, fi os.FileInfo)) {
+	file, err := os.Open(c.path(p))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return
+	}
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		f(lineno, line, fi)
+	}
+}
+
+func (c *collector) add(typ, p string, lineno int, command, owner string, fi os.FileInfo) {
+	if owner == "" {
+		owner = c.owner(fi)
+	}
+	c.entries = append(c.entries, entry{
+		Type:    typ,
+		Path:    p,
+		Line:    lineno,
+		Command: command,
+		Owner:   owner,
+		Mtime:   fi.ModTime(),
+	})
+}
+
+// addFile adds an entry for a file that is executed as a whole,
+// e.g. an init script.
+func (c *collector) addFile(typ, p string) {
+	fi, err := os.Stat(c.path(p))
+	if err != nil {
+		return
+	}
+	c.add(typ, p, 0, p, "", fi)
+}
+
+func (c *collector) readPasswd() {
+	c.readLines("/etc/passwd", func(_ int, line string, _ os.FileInfo) {
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 {
+			return
+		}
+		c.users[fields[2]] = fields[0]
+		if home := fields[5]; home != "" && home != "/" {
+			if _, ok := c.homes[home]; !ok {
+				c.homes[home] = fields[0]
+			}
+		}
+	})
+	if len(c.homes) > 0 {
+		return
+	}
+	for _, dir := range []string{"/root", "/home/*"} {
+		matches, _ := filepath.Glob(c.path(dir))
+		for _, m := range matches {
+			rel, _ := filepath.Rel(c.root, m)
+			c.homes["/"+filepath.ToSlash(rel)] = filepath.Base(m)
+		}
+	}
+}
+
+// sortedHomes returns home directories in a stable order.
+func (c *collector) sortedHomes() []string {
+	var homes []string
+	for h := range c.homes {
+		homes = append(homes, h)
+	}
+	sort.Strings(homes)
+	return homes
+}
+
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\s*=`)
+
+// skipFields returns line without its first n whitespace-separated
+// fields, and the fields that were skipped.
+func skipFields(line string, n int) (string, []string) {
+	var skipped []string
+	for i := 0; i < n; i++ {
+		line = strings.TrimLeft(line, " \t")
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			return "", nil
+		}
+		skipped = append(skipped, line[:end])
+		line = line[end:]
+	}
+	return strings.TrimSpace(line), skipped
+}
+
+// parseCronLine returns the command of a crontab line and, for
+// system crontabs, the user it runs as.
+func parseCronLine(line string, system bool) (user, command string, ok bool) {
+	if envAssignment.MatchString(line) {
+		return "", "", false
+	}
+	n := 5
+	if strings.HasPrefix(line, "@") {
+		n = 1
+	}
+	if system {
+		n++
+	}
+	command, skipped := skipFields(line, n)
+	if command == "" {
+		return "", "", false
+	}
+	if system {
+		user = skipped[n-1]
+	}
+	return user, command, true
+}
+
+func (c *collector) cron() {
+	addCrontab := func(p string, system bool, owner string) {
+		c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+			user, command, ok := parseCronLine(line, system)
+			if !ok {
+				return
+			}
+			if !system {
+				user = owner
+			}
+			c.add("cron", p, lineno, command, user, fi)
+		})
+	}
+	addCrontab("/etc/crontab", true, "")
+	for _, p := range c.glob("/etc/cron.d/*") {
+		addCrontab(p, true, "")
+	}
+	for _, pattern := range []string{"/var/spool/cron/crontabs/*", "/var/spool/cron/*"} {
+		for _, p := range c.glob(pattern) {
+			addCrontab(p, false, filepath.Base(p))
+		}
+	}
+	for _, period := range []string{"hourly", "daily", "weekly", "monthly", "yearly"} {
+		for _, p := range c.glob("/etc/cron." + period + "/*") {
+			if filepath.Base(p) == ".placeholder" {
+				continue
+			}
+			c.addFile("cron", p)
+		}
+	}
+}
+
+func (c *collector) anacron() {
+	c.readLines("/etc/anacrontab", func(lineno int, line string, fi os.FileInfo) {
+		if envAssignment.MatchString(line) {
+			return
+		}
+		// period delay job-identifier command
+		if command, _ := skipFields(line, 3); command != "" {
+			c.add("anacron", "/etc/anacrontab", lineno, command, "root", fi)
+		}
+	})
+}
+
+var systemdExec = regexp.MustCompile(`^(Exec[A-Za-z]+)\s*=\s*(.*)$`)
+
+// systemdUnit adds entries for the commands of a service unit (or a
+// drop-in file) and for the activation of a timer unit.
+func (c *collector) systemdUnit(p, owner string) {
+	isTimer := strings.HasSuffix(p, ".timer")
+	var schedule []string
+	unit := strings.TrimSuffix(filepath.Base(p), ".timer") + ".service"
+	var timerInfo os.FileInfo
+	c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+		if isTimer {
+			timerInfo = fi
+			if i := strings.Index(line, "="); i > 0 {
+				key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+				switch {
+				case key == "Unit":
+					unit = value
+				case strings.HasPrefix(key, "On"):
+					schedule = append(schedule, key+"="+value)
+				}
+			}
+			return
+		}
+		if m := systemdExec.FindStringSubmatch(line); m != nil && m[2] != "" {
+			// Strip special executable prefixes (-, @, :, +, !)
+			command := strings.TrimLeft(m[2], "-@:+!")
+			c.add("systemd", p, lineno, command, owner, fi)
+		}
+	})
+	if isTimer && timerInfo != nil {
+		c.add("systemd", p, 0, unit+" ("+strings.Join(schedule, " ")+")", owner, timerInfo)
+	}
+}
+
+func (c *collector) systemd() {
+	scanDir := func(dir, owner string) {
+		for _, suffix := range []string{"*.service", "*.timer", "*.service.d/*.conf"} {
+			for _, p := range c.glob(dir + "/" + suffix) {
+				c.systemdUnit(p, owner)
+			}
+		}
+	}
+	for _, dir := range systemdDirs {
+		scanDir(dir, "")
+	}
+	for _, home := range c.sortedHomes() {
+		scanDir(home+"/.config/systemd/user", c.homes[home])
+	}
+}
+
+func (c *collector) sysv() {
+	for _, pattern := range []string{"/etc/init.d/*", "/etc/rc.d/init.d/*"} {
+		for _, p := range c.glob(pattern) {
+			c.addFile("sysv", p)
+		}
+	}
+}
+
+func (c *collector) rcLocal() {
+	for _, p := range []string{"/etc/rc.local", "/etc/rc.d/rc.local"} {
+		c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+			if line == "exit 0" {
+				return
+			}
+			c.add("rc.local", p, lineno, line, "", fi)
+		})
+	}
+}
+
+func (c *collector) profiles() {
+	addProfile := func(p, owner string) {
+		c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+			c.add("profile", p, lineno, line, owner, fi)
+		})
+	}
+	for _, pattern := range systemProfiles {
+		for _, p := range c.glob(pattern) {
+			addProfile(p, "")
+		}
+	}
+	for _, home := range c.sortedHomes() {
+		for _, name := range userProfiles {
+			addProfile(home+"/"+name, "")
+		}
+	}
+}
+
+func (c *collector) xdgAutostart() {
+	addDesktop := func(p string) {
+		c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+			if strings.HasPrefix(line, "Exec=") {
+				c.add("xdg-autostart", p, lineno, strings.TrimPrefix(line, "Exec="), "", fi)
+			}
+		})
+	}
+	for _, p := range c.glob("/etc/xdg/autostart/*.desktop") {
+		addDesktop(p)
+	}
+	for _, home := range c.sortedHomes() {
+		for _, p := range c.glob(home + "/.config/autostart/*.desktop") {
+			addDesktop(p)
+		}
+	}
+}
+
+var udevRun = regexp.MustCompile(`\b(RUN|PROGRAM)\b|\bIMPORT\{program\}`)
+
+func (c *collector) udev() {
+	for _, dir := range udevDirs {
+		for _, p := range c.glob(dir + "/*.rules") {
+			c.readLines(p, func(lineno int, line string, fi os.FileInfo) {
+				if udevRun.MatchString(line) {
+					c.add("udev", p, lineno, line, "", fi)
+				}
+			})
+		}
+	}
+}
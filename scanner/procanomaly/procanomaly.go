@@ -0,0 +1,226 @@
+// +build linux
+
+package procanomaly
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+)
+
+func init() { scanner.RegisterProcScanner(&procScanner{proc: procfs.Default}) }
+
+// procScanner looks for properties of running processes that are
+// typical for malware, independent of YARA rules.
+type procScanner struct {
+	proc procfs.FS
+}
+
+func (s *procScanner) Name() string { return "Proc-Anomaly" }
+
+func (s *procScanner) Init() error { return nil }
+
+type anomaly struct {
+	rule    string
+	message string
+	extra   []string
+}
+
+var (
+	suspiciousDirs = []string{"/tmp/", "/var/tmp/", "/dev/shm/"}
+	searchPath     = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+	// kthreadName matches names of common kernel threads.
+	kthreadName = regexp.MustCompile(`^\[?(kworker|ksoftirqd|kthreadd|migration|rcu_[a-z]+|watchdog|kswapd|kauditd|khugepaged|kdevtmpfs|kcompactd|kblockd|ksmd|jbd2|cpuhp|idle_inject|oom_reaper|writeback|netns|kintegrityd|scsi_eh|irq)([/_:\-0-9].*)?\]?$`)
+)
+
+// suspiciousLocation returns a description if exe is located in a
+// world-writable temporary directory or in a hidden directory.
+func suspiciousLocation(exe string) string {
+	for _, dir := range suspiciousDirs {
+		if strings.HasPrefix(exe, dir) {
+			return "temporary directory " + dir
+		}
+	}
+	for _, elem := range strings.Split(path.Dir(exe), "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." && elem != ".." {
+			return "hidden directory " + elem
+		}
+	}
+	return ""
+}
+
+// argvMismatch reports whether argv[0] does not refer to the
+// executable that is actually running. Processes that change their
+// title (e.g. "sshd: user@pts/0") and names that resolve to the
+// executable via symlinks or $PATH are accepted. Paths are resolved
+// in the process's mount namespace (/proc/<pid>/root), so that
+// processes running in containers are handled correctly.
+func (s *procScanner) argvMismatch(p procfs.Proc, cmdline []string, exe string) bool {
+	if len(cmdline) == 0 {
+		return false
+	}
+	argv0 := strings.TrimPrefix(cmdline[0], "-")
+	if argv0 == "" || strings.ContainsAny(argv0, " :[") {
+		return false
+	}
+	exeBase, argvBase := path.Base(exe), path.Base(argv0)
+	// e.g. python3 -> /usr/bin/python3.11
+	if strings.HasPrefix(exeBase, argvBase) {
+		return false
+	}
+	exeInfo, err := os.Stat(p.Path("exe"))
+	if err != nil {
+		// The process has exited or cannot be inspected.
+		return false
+	}
+	root := p.Path("root")
+	var candidates []string
+	switch {
+	case path.IsAbs(argv0):
+		candidates = []string{argv0}
+	case strings.Contains(argv0, "/"):
+		if cwd, err := p.Cwd(); err == nil {
+			candidates = []string{path.Join(cwd, argv0)}
+		}
+	default:
+		for _, dir := range searchPath {
+			candidates = append(candidates, path.Join(dir, argv0))
+		}
+	}
+	for _, c := range candidates {
+		if fi, err := os.Stat(filepath.Join(root, c)); err == nil && os.SameFile(fi, exeInfo) {
+			return false
+		}
+	}
+	return true
+}
+
+// rwxAnonymous returns writable and executable memory regions that
+// are not backed by a file.
+func rwxAnonymous(maps []procfs.Mapping) (regions []string) {
+	for _, m := range maps {
+		if strings.HasPrefix(m.Perms, "rwx") && m.Anonymous() {
+			r := fmt.Sprintf("%x-%x", m.Start, m.End)
+			if m.Path != "" {
+				r += " " + m.Path
+			}
+			regions = append(regions, r)
+		}
+	}
+	return
+}
+
+// check returns the anomalies found for a process.
+func (s *procScanner) check(p procfs.Proc, info procfs.Info) (anomalies []anomaly) {
+	if info.Stat.Flags&procfs.FlagKernelThread != 0 {
+		return nil
+	}
+	add := func(rule, message string, extra ...string) {
+		anomalies = append(anomalies, anomaly{rule, message, extra})
+	}
+	exe := info.Exe
+	if exe != "" {
+		deleted := strings.HasSuffix(exe, " (deleted)")
+		exe = strings.TrimSuffix(exe, " (deleted)")
+		switch {
+		case strings.HasPrefix(exe, "/memfd:"):
+			add("proc_exe_memfd", "executable is an anonymous memory file (memfd)")
+		case deleted:
+			add("proc_exe_deleted", "executable has been deleted")
+		}
+		if where := suspiciousLocation(exe); where != "" {
+			add("proc_exe_suspicious_location", "executable is located in "+where)
+		}
+		if !strings.HasPrefix(exe, "/memfd:") && s.argvMismatch(p, info.Cmdline, exe) {
+			add("proc_exe_argv_mismatch", "argv[0] does not match executable", "argv0", info.Cmdline[0])
+		}
+		// Kernel threads do not have an executable. Daemons that
+		// happen to share a name with a kernel thread (e.g.
+		// watchdog) are recognized by their executable's name.
+		name := info.Comm
+		if len(info.Cmdline) > 0 && strings.HasPrefix(info.Cmdline[0], "[") {
+			name = info.Cmdline[0]
+		}
+		if kthreadName.MatchString(name) && path.Base(exe) != name {
+			add("proc_kthread_mimicry", "userland process uses kernel thread name "+name)
+		}
+	}
+	if maps, err := p.Maps(); err == nil {
+		if regions := rwxAnonymous(maps); len(regions) > 0 {
+			add("proc_rwx_anonymous",
+				fmt.Sprintf("%d anonymous memory regions are writable and executable", len(regions)),
+				"regions", strings.Join(regions, "|"))
+		}
+	}
+	return
+}
+
+func username(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// context returns report fields describing the process and its
+// parent.
+func (s *procScanner) context(info procfs.Info) []string {
+	extra := []string{
+		"process_key", info.Key,
+		"PID", strconv.Itoa(info.PID),
+		"PPID", strconv.Itoa(info.Stat.PPID),
+		"Process", info.Comm,
+		"pathexe", info.Exe,
+		"cmdline", strings.Join(info.Cmdline, " "),
+		"username", username(info.UID),
+	}
+	if info.Stat.PPID > 0 {
+		parent := s.proc.Proc(info.Stat.PPID).Info()
+		extra = append(extra,
+			"Parent_process_key", parent.Key,
+			"Parent_Process", parent.Comm,
+			"Parent_pathexe", parent.Exe,
+			"Parent_cmdline", strings.Join(parent.Cmdline, " "),
+			"Parent_username", username(parent.UID),
+		)
+	}
+	return extra
+}
+
+func stringInSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *procScanner) ScanProc(pid int32) error {
+	p := s.proc.Proc(int(pid))
+	info := p.Info()
+	if stringInSlice(info.Comm, config.ProcIgnoreList) {
+		return scanner.ErrSkipped
+	}
+	anomalies := s.check(p, info)
+	if len(anomalies) == 0 {
+		return nil
+	}
+	ctx := s.context(info)
+	for _, a := range anomalies {
+		message := fmt.Sprintf("Process anomaly on %s[%d]: %s", info.Comm, pid, a.message)
+		extra := append([]string{"rule", a.rule}, a.extra...)
+		report.AddProcInfo("proc_anomaly", message, append(extra, ctx...)...)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// +build linux
+
+package procanomaly
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+func TestCheck(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	const stat = "%d (%s) S 1 1 1 0 -1 %d 0 0 0 0 0 0 0 0 20 0 1 0 100 0 0\n"
+	proc := func(pid int, comm, exe, cmdline string, flags int, maps string) {
+		dir := "proc/" + strconv.Itoa(pid) + "/"
+		tr.Write(dir+"comm", comm+"\n")
+		tr.Write(dir+"cmdline", cmdline)
+		tr.Write(dir+"stat", fmt.Sprintf(stat, pid, comm, flags))
+		tr.Write(dir+"maps", maps)
+		// Executables and the root directory resolve into the
+		// fake tree, as they would in a container.
+		tr.Symlink("../..", dir+"root")
+		if _, err := os.Stat(tr.Path(exe)); exe != "" && err == nil {
+			exe = "../.." + exe
+		}
+		if exe != "" {
+			tr.Symlink(exe, dir+"exe")
+		}
+	}
+	tr.Write("usr/bin/vim.basic", "")
+	tr.Symlink("vim.basic", "usr/bin/vi")
+	tr.Write("usr/bin/python3.11", "")
+	tr.Write("usr/sbin/watchdog", "")
+	tr.Write("tmp/.x/miner", "")
+	tr.Write("usr/bin/sleep", "")
+
+	const libc = "7f0000000000-7f0000001000 r-xp 00000000 fd:01 1234 /usr/lib/libc.so.6\n"
+	proc(10, "kworker/0:1", "", "", procfs.FlagKernelThread, "")
+	proc(11, "vi", "/usr/bin/vim.basic", "vi\x00/etc/hosts\x00", 0, libc)
+	proc(12, "python3", "/usr/bin/python3.11", "python3\x00x.py\x00", 0, libc)
+	proc(13, "sshd", "/usr/sbin/sshd", "sshd: root@pts/0\x00", 0, libc)
+	proc(14, "watchdog", "/usr/sbin/watchdog", "/usr/sbin/watchdog\x00", 0, libc)
+	proc(20, "miner", "/tmp/.x/miner (deleted)", "/tmp/.x/miner\x00", 0, libc)
+	proc(21, "x", "/memfd:x (deleted)", "x\x00", 0, libc)
+	proc(22, "kworker/1:0", "/usr/bin/sleep", "[kworker/1:0]\x00", 0, libc)
+	proc(23, "node", "/usr/bin/sleep", "sleep\x00", 0, libc+
+		"7f0000100000-7f0000200000 rwxp 00000000 00:00 0 \n")
+	proc(24, "bash", "/usr/bin/sleep", "/usr/bin/bash\x00", 0, libc)
+
+	s := &procScanner{proc: procfs.FS(tr.Path("proc"))}
+	expected := map[int][]string{
+		10: nil,
+		11: nil,
+		12: nil,
+		13: nil,
+		14: nil,
+		20: {"proc_exe_deleted", "proc_exe_suspicious_location"},
+		21: {"proc_exe_memfd"},
+		22: {"proc_kthread_mimicry"},
+		23: {"proc_rwx_anonymous"},
+		24: {"proc_exe_argv_mismatch"},
+	}
+	for pid, rules := range expected {
+		p := s.proc.Proc(pid)
+		var got []string
+		for _, a := range s.check(p, p.Info()) {
+			got = append(got, a.rule)
+		}
+		if !reflect.DeepEqual(got, rules) {
+			t.Errorf("pid %d: got %v, expected %v", pid, got, rules)
+		}
+	}
+}
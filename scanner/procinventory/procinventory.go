@@ -0,0 +1,166 @@
+// +build linux
+
+package procinventory
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/report"
+	"github.com/spyre-project/spyre/scanner"
+)
+
+func init() { scanner.RegisterSystemScanner(&systemScanner{proc: procfs.Default}) }
+
+// systemScanner records every running process once, so that the
+// process tree can be reconstructed from the report and findings can
+// refer to processes by their process_key.
+type systemScanner struct {
+	proc procfs.FS
+}
+
+func (s *systemScanner) Name() string { return "Process-Inventory" }
+
+func (s *systemScanner) Init() error { return nil }
+
+type hashes struct{ md5, sha1, sha256 string }
+
+// hashFile computes the MD5, SHA1 and SHA256 hashes of a file.
+func hashFile(name string) (hashes, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return hashes{}, err
+	}
+	defer f.Close()
+	h := []hash.Hash{md5.New(), sha1.New(), sha256.New()}
+	if _, err := io.Copy(io.MultiWriter(h[0], h[1], h[2]), f); err != nil {
+		return hashes{}, err
+	}
+	return hashes{
+		hex.EncodeToString(h[0].Sum(nil)),
+		hex.EncodeToString(h[1].Sum(nil)),
+		hex.EncodeToString(h[2].Sum(nil)),
+	}, nil
+}
+
+// fileKey identifies an executable file, so that executables shared
+// by many processes are only hashed once.
+type fileKey struct{ dev, ino uint64 }
+
+// hashExe hashes the executable of a process, read through
+// /proc/<pid>/exe, which also works for deleted files. Hashes are
+// cached, since many processes share the same executable.
+func hashExe(name string, cache map[fileKey]hashes) (hashes, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return hashes{}, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return hashFile(name)
+	}
+	key := fileKey{uint64(st.Dev), uint64(st.Ino)}
+	if h, ok := cache[key]; ok {
+		return h, nil
+	}
+	h, err := hashFile(name)
+	if err == nil {
+		cache[key] = h
+	}
+	return h, err
+}
+
+func username(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// inventory returns the report fields for a process. ok is false if
+// the process has exited.
+func (s *systemScanner) inventory(pid int, boot time.Time, cache map[fileKey]hashes) (info procfs.Info, extra []string, ok bool) {
+	p := s.proc.Proc(pid)
+	if info = p.Info(); info.Key == "" {
+		return info, nil, false
+	}
+	cwd, _ := p.Cwd()
+	extra = []string{
+		"process_key", info.Key,
+		"PID", strconv.Itoa(pid),
+		"PPID", strconv.Itoa(info.Stat.PPID),
+		"Process", info.Comm,
+		"pathexe", info.Exe,
+		"cmdline", strings.Join(info.Cmdline, " "),
+		"uid", info.UID,
+		"username", username(info.UID),
+		"cwd", cwd,
+	}
+	if info.Stat.PPID > 0 {
+		if key := s.proc.Proc(info.Stat.PPID).Key(); key != "" {
+			extra = append(extra, "Parent_process_key", key)
+		}
+	}
+	if !boot.IsZero() {
+		extra = append(extra, "time_process_start", report.FormatTime(procfs.StartTime(boot, info.Stat.StartTime)))
+	}
+	if info.Exe != "" {
+		h, err := hashExe(p.Path("exe"), cache)
+		if err != nil {
+			log.Debugf("Could not hash executable of pid %d: %v", pid, err)
+		}
+		extra = append(extra, "Filehash", h.md5, "Filehash1", h.sha1, "Filehash256", h.sha256)
+	}
+	if ns, err := p.Namespaces(); err == nil {
+		var names []string
+		for name := range ns {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			extra = append(extra, "ns_"+name, ns[name])
+		}
+	}
+	return info, extra, true
+}
+
+func (s *systemScanner) Scan() error {
+	if !config.ProcInventory {
+		return nil
+	}
+	pids, err := s.proc.Pids()
+	if err != nil {
+		return err
+	}
+	boot, err := s.proc.BootTime()
+	if err != nil {
+		log.Debugf("Could not read boot time: %v", err)
+	}
+	cache := make(map[fileKey]hashes)
+	var n int
+	for _, pid := range pids {
+		info, extra, ok := s.inventory(pid, boot, cache)
+		if !ok {
+			continue
+		}
+		report.AddProcInfo("proc_inventory", fmt.Sprintf("Process %s[%d]", info.Comm, pid), extra...)
+		n++
+	}
+	log.Infof("Recorded %d processes", n)
+	return nil
+}
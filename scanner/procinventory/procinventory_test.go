@@ -0,0 +1,64 @@
+// +build linux
+
+package procinventory
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/spyre-project/spyre/procfs"
+	"github.com/spyre-project/spyre/procfs/procfstest"
+)
+
+func TestInventory(t *testing.T) {
+	tr := procfstest.New(t)
+	defer tr.Remove()
+	const stat = "%d (%s) S %d 1 1 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 %d 0 0\n"
+	proc := func(pid, ppid int, comm, exe, cmdline string, start int) {
+		dir := "proc/" + strconv.Itoa(pid) + "/"
+		tr.Write(dir+"comm", comm+"\n")
+		tr.Write(dir+"cmdline", cmdline)
+		tr.Write(dir+"stat", fmt.Sprintf(stat, pid, comm, ppid, start))
+		tr.Write(dir+"status", "Uid:\t0\t0\t0\t0\n")
+		tr.Symlink(tr.Path(exe), dir+"exe")
+		tr.Symlink("/", dir+"cwd")
+		tr.Symlink("pid:[4026531836]", dir+"ns/pid")
+	}
+	tr.Write("proc/sys/kernel/random/boot_id", "0123abcd-0000-0000-0000-000000000000\n")
+	tr.Write("proc/stat", "btime 1600000000\n")
+	tr.Write("bin/sh", "")
+	proc(1, 0, "init", "bin/sh", "/sbin/init\x00", 1)
+	proc(42, 1, "sh", "bin/sh", "sh\x00-c\x00id\x00", 250)
+
+	s := &systemScanner{proc: procfs.FS(tr.Path("proc"))}
+	boot, _ := s.proc.BootTime()
+	_, extra, ok := s.inventory(42, boot, make(map[fileKey]hashes))
+	if !ok {
+		t.Fatal("process 42 not found")
+	}
+	fields := make(map[string]string)
+	for i := 0; i+1 < len(extra); i += 2 {
+		fields[extra[i]] = extra[i+1]
+	}
+	for k, v := range map[string]string{
+		"process_key":        "0123abcd-42-250",
+		"Parent_process_key": "0123abcd-1-1",
+		"PPID":               "1",
+		"cmdline":            "sh -c id",
+		"cwd":                "/",
+		"username":           "root",
+		"time_process_start": time.Unix(1600000002, 500000000).UTC().Format(time.RFC3339Nano),
+		"Filehash":           "d41d8cd98f00b204e9800998ecf8427e",
+		"Filehash256":        "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		"ns_pid":             "pid:[4026531836]",
+	} {
+		if fields[k] != v {
+			t.Errorf("%s: got %q, expected %q", k, fields[k], v)
+		}
+	}
+	if _, _, ok := s.inventory(43, boot, nil); ok {
+		t.Error("missing process 43 was reported")
+	}
+}
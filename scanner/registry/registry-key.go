@@ -200,16 +200,18 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 	}
 	defer k.Close()
 	var datem = ""
+	var lastwrite = ""
 	ki, err := k.Stat()
 	if err == nil {
 		time_tmp := ki.ModTime()
 		datem = time_tmp.String()
+		lastwrite = report.FormatTime(time_tmp)
 	}
 	if typex == 0 {
 		//key name exist
 		message := fmt.Sprintf("Found registry rule %s: [%s]",desc, key)
 		report.AddRegistryInfo("ioc_on_registry", message,
-			"rule", desc, "key_path", key, "real_date", datem)
+			"rule", desc, "key_path", key, "real_date", datem, "time_last_write", lastwrite)
 		return
 	}
 	switch typex {
@@ -228,7 +230,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 				if res {
 					message := fmt.Sprintf("Found registry rule %s: [%s]%s",desc, key, param)
 					report.AddRegistryInfo("ioc_on_registry", message,
-						"rule", desc, "key_path", key, "key_name", param, "real_date", datem)
+						"rule", desc, "key_path", key, "key_name", param, "real_date", datem, "time_last_write", lastwrite)
 					return
 				}
 			}
@@ -242,7 +244,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 				if res {
 					message := fmt.Sprintf("Found registry rule %s: [%s]%s -> %s",desc, key, param, val)
 					report.AddRegistryInfo("ioc_on_registry", message,
-						"rule", desc, "key_path", key, "key_name", param, "values", val, "real_date", datem)
+						"rule", desc, "key_path", key, "key_name", param, "values", val, "real_date", datem, "time_last_write", lastwrite)
 					return
 				}
 			}
@@ -260,7 +262,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 				if matched {
 					message := fmt.Sprintf("Found registry rule %s: [%s]%s -> %s",desc, key, param, val)
 					report.AddRegistryInfo("ioc_on_registry", message,
-						"rule", desc, "key_path", key, "key_name", param, "values", val, "real_date", datem)
+						"rule", desc, "key_path", key, "key_name", param, "values", val, "real_date", datem, "time_last_write", lastwrite)
 					return
 				}
 			}
@@ -276,7 +278,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 		//key name exist
 		message := fmt.Sprintf("Found registry rule %s: [%s]%s",desc, key, name)
 		report.AddRegistryInfo("ioc_on_registry", message,
-			"rule", desc, "key_path", key, "key_name", name, "real_date", datem)
+			"rule", desc, "key_path", key, "key_name", name, "real_date", datem, "time_last_write", lastwrite)
 		return
 	}
 	if typex == 3 {
@@ -285,7 +287,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 		if res {
 			message := fmt.Sprintf("Found registry rule %s: [%s]%s -> %s",desc, key, name, val)
 			report.AddRegistryInfo("ioc_on_registry", message,
-				"rule", desc, "key_path", key, "key_name", name, "values", val, "real_date", datem)
+				"rule", desc, "key_path", key, "key_name", name, "values", val, "real_date", datem, "time_last_write", lastwrite)
 			return
 		}
 		return
@@ -299,7 +301,7 @@ func keyCheck(key string, name string, valuex string, typex int, desc string, ba
 		if matched {
 			message := fmt.Sprintf("Found registry rule %s: [%s]%s -> %s",desc, key, name, val)
 			report.AddRegistryInfo("ioc_on_registry", message,
-				"rule", desc, "key_path", key, "key_name", name, "values", val, "real_date", datem)
+				"rule", desc, "key_path", key, "key_name", name, "values", val, "real_date", datem, "time_last_write", lastwrite)
 			return
 		}
 		return
@@ -0,0 +1,64 @@
+package scanner
+
+import (
+	"github.com/spyre-project/spyre/report"
+
+	"errors"
+	"sort"
+)
+
+// ErrSkipped can be returned by a scan module to signal that it has
+// deliberately not scanned an item (e.g. because it is found on an
+// ignore list). It is counted, but not treated as an error.
+var ErrSkipped = errors.New("skipped")
+
+// ModuleStats contains counters that are kept for each scan module.
+type ModuleStats struct {
+	Scanned, Skipped, Errors, Matches int
+}
+
+var (
+	moduleStats = make(map[string]*ModuleStats)
+	moduleOrder []string
+)
+
+// track calls scan for module name and updates its statistics. Matches
+// are determined from the number of findings that have been reported
+// while scan was running. ErrSkipped is not passed on.
+func track(name string, scan func() error) error {
+	st, ok := moduleStats[name]
+	if !ok {
+		st = &ModuleStats{}
+		moduleStats[name] = st
+		moduleOrder = append(moduleOrder, name)
+	}
+	before := report.Findings()
+	err := scan()
+	st.Matches += report.Findings() - before
+	switch err {
+	case nil:
+		st.Scanned++
+	case ErrSkipped:
+		st.Skipped++
+		err = nil
+	default:
+		st.Errors++
+	}
+	return err
+}
+
+// ModuleNames returns the names of all modules for which statistics
+// have been recorded, sorted by name.
+func ModuleNames() []string {
+	names := append([]string{}, moduleOrder...)
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the statistics for the scan module name.
+func Stats(name string) ModuleStats {
+	if st, ok := moduleStats[name]; ok {
+		return *st
+	}
+	return ModuleStats{}
+}
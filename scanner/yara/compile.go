@@ -10,7 +10,10 @@ import (
 	"io/ioutil"
 	"path/filepath"
 
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"hash"
 	"fmt"
 	"strings"
 )
@@ -22,6 +25,9 @@ type includeState struct {
 	fs       afero.Fs
 	cwd      string
 	included []string
+	// digest, if set, receives the names and contents of all
+	// included files.
+	digest hash.Hash
 }
 
 func (is *includeState) IncludeCallback(name, filename, namespace string) []byte {
@@ -51,6 +57,10 @@ func (is *includeState) IncludeCallback(name, filename, namespace string) []byte
 		log.Errorf("yara: init: Read from %s: %v", name, err)
 		return nil
 	}
+	if is.digest != nil {
+		fmt.Fprintf(is.digest, "%s\n%d\n", name, len(buf))
+		is.digest.Write(buf)
+	}
 	return buf
 }
 
@@ -86,19 +96,22 @@ var extvars = map[int]extvardefs{
 	evtxscan: extvardefs{},
 }
 
-func compile(purpose int, inputfiles []string) (*yr.Rules, error) {
+// compile compiles the rule files for a scan purpose. Besides the
+// rules, it returns a fingerprint over all rule files that have been
+// read.
+func compile(purpose int, inputfiles []string) (*yr.Rules, string, error) {
 	var c *yr.Compiler
 	var err error
 	var paths []string
 	if c, err = yr.NewCompiler(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	is := &includeState{fs: config.Fs}
+	is := &includeState{fs: config.Fs, digest: sha256.New()}
 	c.SetIncludeCallback(is.IncludeCallback)
 
 	for k, v := range extvars[purpose] {
 		if err = c.DefineVariable(k, v); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	}
 
@@ -106,14 +119,14 @@ func compile(purpose int, inputfiles []string) (*yr.Rules, error) {
 	for _, path := range inputfiles {
 		if fi, err := config.Fs.Stat(path); err != nil {
 			log.Errorf("yara: init: %v", err)
-			return nil, err
+			return nil, "", err
 		} else if fi.IsDir() {
 			log.Errorf("yara: init: %s is a directory", path)
 		}
 		paths = append(paths, path)
 	}
 	if len(paths) == 0 {
-		return nil, errors.New("No YARA rule files found")
+		return nil, "", errors.New("No YARA rule files found")
 	}
 	for _, path := range paths {
 		// We use the include callback function to actually read files
@@ -121,7 +134,7 @@ func compile(purpose int, inputfiles []string) (*yr.Rules, error) {
 		// name.
 		log.Debugf("yara: init: Adding %s", path)
 		if err = c.AddString(fmt.Sprintf(`include "%s"`, path), ""); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	}
 	purposeStr := [...]string{"file", "process", "evtx"}[purpose]
@@ -131,7 +144,7 @@ func compile(purpose int, inputfiles []string) (*yr.Rules, error) {
 			log.Errorf("YARA compiler error in %s ruleset: %s:%d %s",
 				purposeStr, e.Filename, e.Line, e.Text)
 		}
-		return nil, fmt.Errorf("%d YARA compiler errors(s) found, rejecting %s ruleset",
+		return nil, "", fmt.Errorf("%d YARA compiler errors(s) found, rejecting %s ruleset",
 			len(c.Errors), purposeStr)
 	}
 	if len(c.Warnings) > 0 {
@@ -140,12 +153,12 @@ func compile(purpose int, inputfiles []string) (*yr.Rules, error) {
 				purposeStr, w.Filename, w.Line, w.Text)
 		}
 		if config.YaraFailOnWarnings {
-			return nil, fmt.Errorf("%d YARA compiler warning(s) found, rejecting %s ruleset",
+			return nil, "", fmt.Errorf("%d YARA compiler warning(s) found, rejecting %s ruleset",
 				len(c.Warnings), purposeStr)
 		}
 	}
 	if len(rs.GetRules()) == 0 {
-		return nil, errors.New("No YARA rules defined")
+		return nil, "", errors.New("No YARA rules defined")
 	}
-	return rs, nil
+	return rs, hex.EncodeToString(is.digest.Sum(nil)), nil
 }
@@ -21,7 +21,7 @@ func (s *evtxScanner) Name() string { return "YARA-evtx" }
 
 func (s *evtxScanner) Init() error {
 	var err error
-	s.rules, err = compile(evtxscan, config.YaraEvtxRules)
+	s.rules, _, err = compile(evtxscan, config.YaraEvtxRules)
 	return err
 }
 
@@ -73,9 +73,13 @@ func (s *evtxScanner) ScanEvtx(evt string, jsonval []byte) error {
          }
 			 }
 		}
+		event_time := ""
+		if t, err := time.Parse(time.RFC3339Nano, event_date); err == nil {
+			event_time = report.FormatTime(t)
+		}
 		message := m.Rule + " (yara) matched on event windows: " + event_id + "(" + source_name + ")" + "[" + event_level + "]"
 		report.AddEvtxInfo(evt, "yara_on_eventlog", message,
-			"rule", m.Rule, "event_level", event_level, "event_identifier", event_id, "source_name", source_name, "real_date", event_date, "event_sid", event_sid)
+			"rule", m.Rule, "event_level", event_level, "event_identifier", event_id, "source_name", source_name, "real_date", event_date, "time_event", event_time, "event_sid", event_sid)
 	}
 	return err
 }
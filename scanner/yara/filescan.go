@@ -10,27 +10,32 @@ import (
 	"github.com/spf13/afero"
 
 	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/platform"
 	"github.com/spyre-project/spyre/report"
 	"github.com/spyre-project/spyre/scanner"
 
 	"io/ioutil"
-	"os"
 	"path/filepath"
 	"time"
 )
 
 func init() { scanner.RegisterFileScanner(&fileScanner{}) }
 
-type fileScanner struct{ rules *yr.Rules }
+type fileScanner struct {
+	rules       *yr.Rules
+	fingerprint string
+}
 
 func (s *fileScanner) Name() string { return "YARA-file" }
 
 func (s *fileScanner) Init() error {
 	var err error
-	s.rules, err = compile(filescan, config.YaraFileRules)
+	s.rules, s.fingerprint, err = compile(filescan, config.YaraFileRules)
 	return err
 }
 
+func (s *fileScanner) Fingerprint() string { return s.fingerprint }
+
 func (s *fileScanner) ScanFile(f afero.File) error {
 	var (
 		matches yr.MatchRules
@@ -64,11 +69,24 @@ func (s *fileScanner) ScanFile(f afero.File) error {
 	fi, err := f.Stat()
 	var datem = ""
 	var content_file = ""
+	var times []string
 	if err == nil {
 		date_tmp := fi.ModTime()
 		datem = date_tmp.String()
+		atime, ctime, btime := platform.FileTimes(fi)
+		times = []string{
+			"time_modified", report.FormatTime(date_tmp),
+			"time_accessed", report.FormatTime(atime),
+			"time_changed", report.FormatTime(ctime),
+			"time_created", report.FormatTime(btime),
+		}
 	}
-	if f, ok := f.(*os.File); ok {
+	// Files opened from disk (possibly wrapped, e.g. to report a
+	// different name) are scanned through their file descriptor.
+	if f, ok := f.(interface {
+		afero.File
+		Fd() uintptr
+	}); ok {
 		fd := f.Fd()
 		err = s.rules.ScanFileDescriptor(fd, 0, 1*time.Minute, &matches)
 		if matches != nil {
@@ -102,10 +120,10 @@ func (s *fileScanner) ScanFile(f afero.File) error {
     message := m.Rule + " (yara) matched on file: " + f.Name() + " (" + string(md5sum) + ")"
 		if strings.Contains(m.Rule,"_keepfile") {
 		  report.AddFileInfo(f, "yara_on_file", message,
-			  "rule", m.Rule, "Filehash", string(md5sum), "real_date", datem, "Filepath", f.Name(), "string_match", string(matched), "extracted_file", content_file)
+			  append([]string{"rule", m.Rule, "Filehash", string(md5sum), "real_date", datem, "Filepath", f.Name(), "string_match", string(matched), "extracted_file", content_file}, times...)...)
 	  } else {
 			report.AddFileInfo(f, "yara_on_file", message,
-				"rule", m.Rule, "Filehash", string(md5sum), "real_date", datem, "Filepath", f.Name(), "string_match", string(matched))
+				append([]string{"rule", m.Rule, "Filehash", string(md5sum), "real_date", datem, "Filepath", f.Name(), "string_match", string(matched)}, times...)...)
 		}
 	}
 	return err
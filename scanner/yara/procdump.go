@@ -0,0 +1,220 @@
+package yara
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre"
+	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/procfs"
+)
+
+// Process memory dump modes, see --proc-dump.
+const (
+	dumpMatched = "matched"
+	dumpAll     = "all"
+)
+
+func checkDumpMode(mode string) error {
+	switch mode {
+	case "", dumpMatched, dumpAll:
+		return nil
+	default:
+		return errors.New("unknown process dump mode: " + mode)
+	}
+}
+
+// dumpRegion describes a memory region in the map that is written
+// along with the region files.
+type dumpRegion struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Perms     string `json:"perms"`
+	Offset    uint64 `json:"offset"`
+	Dev       string `json:"dev"`
+	Inode     uint64 `json:"inode"`
+	Path      string `json:"path,omitempty"`
+	File      string `json:"file,omitempty"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type dumpMap struct {
+	Hostname string       `json:"hostname"`
+	PID      int          `json:"pid"`
+	Exe      string       `json:"exe"`
+	Cmdline  string       `json:"cmdline"`
+	Rules    []string     `json:"rules"`
+	Time     string       `json:"time"`
+	Regions  []dumpRegion `json:"regions"`
+}
+
+// matchedRegions returns the mappings that contain at least one
+// string match. If regions is set, it contains the mapping for each
+// match as returned by scanRegions; otherwise the mappings are looked
+// up by match address.
+func matchedRegions(maps []procfs.Mapping, matches yr.MatchRules, regions []procfs.Mapping) (selected []procfs.Mapping) {
+	seen := make(map[uint64]bool)
+	add := func(m procfs.Mapping) {
+		if !seen[m.Start] {
+			seen[m.Start] = true
+			selected = append(selected, m)
+		}
+	}
+	for i, match := range matches {
+		if regions != nil {
+			add(regions[i])
+			continue
+		}
+		for _, s := range match.Strings {
+			addr := s.Base + s.Offset
+			for _, m := range maps {
+				if m.Start <= addr && addr < m.End {
+					add(m)
+					break
+				}
+			}
+		}
+	}
+	return
+}
+
+// writeDump copies the given regions from mem to files in dir and
+// writes a map of the regions to dir/maps.json. At most limit bytes
+// are copied in total; regions that do not fit are truncated or left
+// out and marked as such in the map.
+func writeDump(dir string, mem io.ReaderAt, maps []procfs.Mapping, limit int64, info dumpMap) (int64, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, m := range maps {
+		r := dumpRegion{
+			Start:  fmt.Sprintf("0x%x", m.Start),
+			End:    fmt.Sprintf("0x%x", m.End),
+			Perms:  m.Perms,
+			Offset: m.Offset,
+			Dev:    m.Dev,
+			Inode:  m.Inode,
+			Path:   m.Path,
+		}
+		size := int64(m.End - m.Start)
+		if limit > 0 && total+size > limit {
+			size = limit - total
+			r.Truncated = true
+		}
+		if m.Start > math.MaxInt64 || size <= 0 {
+			r.Truncated = true
+			info.Regions = append(info.Regions, r)
+			continue
+		}
+		r.File = fmt.Sprintf("%016x-%016x.bin", m.Start, m.End)
+		f, err := os.OpenFile(filepath.Join(dir, r.File), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return total, err
+		}
+		// Regions may become partially unreadable, e.g. guard
+		// pages; whatever could be read is kept.
+		r.Size, err = io.Copy(f, io.NewSectionReader(mem, int64(m.Start), size))
+		f.Close()
+		if err != nil {
+			r.Error = err.Error()
+		}
+		total += r.Size
+		info.Regions = append(info.Regions, r)
+	}
+	buf, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return total, err
+	}
+	return total, writeFile(filepath.Join(dir, "maps.json"), buf)
+}
+
+func writeFile(name string, buf []byte) error {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// dumpLimit returns the amount of memory that may be dumped for the
+// next process, given the amount already dumped during the scan. 0
+// means no limit; ok is false if the total has been used up.
+func dumpLimit(perProcess, total, dumped int64) (limit int64, ok bool) {
+	if total <= 0 {
+		return perProcess, true
+	}
+	remaining := total - dumped
+	if remaining <= 0 {
+		return 0, false
+	}
+	if perProcess <= 0 || remaining < perProcess {
+		return remaining, true
+	}
+	return perProcess, true
+}
+
+// dumpProcess writes the memory of a process that has been matched by
+// YARA rules to the dump directory, according to --proc-dump. The
+// directory that the dump was written to is returned. Once
+// --proc-dump-total-size has been used up, no more dumps are written.
+func (s *procScanner) dumpProcess(pid int, exe, cmdline string, matches yr.MatchRules, regions []procfs.Mapping) (string, error) {
+	limit, ok := dumpLimit(int64(config.ProcDumpMaxSize), int64(config.ProcDumpTotalSize), s.dumped)
+	if !ok {
+		log.Noticef("Not dumping memory of process %s[%d]: total dump size limit reached", exe, pid)
+		return "", nil
+	}
+	p := procfs.Default.Proc(pid)
+	maps, err := p.Maps()
+	if err != nil {
+		return "", err
+	}
+	if config.ProcDump == dumpMatched {
+		maps = matchedRegions(maps, matches, regions)
+	} else {
+		all := &regionFilter{}
+		var selected []procfs.Mapping
+		for _, m := range maps {
+			if all.match(m) {
+				selected = append(selected, m)
+			}
+		}
+		maps = selected
+	}
+	mem, err := openMem(p)
+	if err != nil {
+		return "", err
+	}
+	defer mem.Close()
+	now := time.Now().UTC()
+	dir := filepath.Join(config.ProcDumpDir,
+		fmt.Sprintf("%s-%d-%s", spyre.Hostname, pid, now.Format("20060102T150405Z")))
+	info := dumpMap{
+		Hostname: spyre.Hostname,
+		PID:      pid,
+		Exe:      exe,
+		Cmdline:  cmdline,
+		Time:     now.Format(time.RFC3339),
+	}
+	for _, m := range matches {
+		info.Rules = append(info.Rules, m.Rule)
+	}
+	n, err := writeDump(dir, mem, maps, limit, info)
+	s.dumped += n
+	return dir, err
+}
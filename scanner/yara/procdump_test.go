@@ -0,0 +1,110 @@
+package yara
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+func TestMatchedRegions(t *testing.T) {
+	maps := []procfs.Mapping{
+		{Start: 0x1000, End: 0x2000, Perms: "r-xp"},
+		{Start: 0x2000, End: 0x4000, Perms: "rw-p"},
+		{Start: 0x8000, End: 0x9000, Perms: "rwxp"},
+	}
+	matches := yr.MatchRules{
+		{Rule: "a", Strings: []yr.MatchString{{Base: 0x2000, Offset: 0x1800}, {Base: 0x8000, Offset: 0x10}}},
+		{Rule: "b", Strings: []yr.MatchString{{Base: 0x2000, Offset: 0x10}}},
+	}
+	got := matchedRegions(maps, matches, nil)
+	if len(got) != 2 || got[0].Start != 0x2000 || got[1].Start != 0x8000 {
+		t.Errorf("by address: got %v", got)
+	}
+	got = matchedRegions(maps, matches, []procfs.Mapping{maps[0], maps[0]})
+	if len(got) != 1 || got[0].Start != 0x1000 {
+		t.Errorf("by region: got %v", got)
+	}
+}
+
+func TestWriteDump(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-procdump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	mem := bytes.NewReader(bytes.Repeat([]byte("0123456789abcdef"), 0x100))
+	maps := []procfs.Mapping{
+		{Start: 0x10, End: 0x20, Perms: "r-xp", Path: "/bin/x"},
+		{Start: 0x100, End: 0x140, Perms: "rw-p"},
+		{Start: 0x200, End: 0x210, Perms: "rw-p"},
+	}
+	total, err := writeDump(dir, mem, maps, 0x30, dumpMap{PID: 42, Rules: []string{"a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 0x30 {
+		t.Errorf("total = %d, want %d", total, 0x30)
+	}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "maps.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m dumpMap
+	if err := json.Unmarshal(buf, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.PID != 42 || len(m.Regions) != 3 {
+		t.Fatalf("unexpected map: %s", buf)
+	}
+	for i, want := range []struct {
+		size      int64
+		truncated bool
+		file      bool
+	}{
+		{0x10, false, true},
+		{0x20, true, true},
+		{0, true, false},
+	} {
+		r := m.Regions[i]
+		if r.Size != want.size || r.Truncated != want.truncated || (r.File != "") != want.file {
+			t.Errorf("region %d: got %+v", i, r)
+		}
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, m.Regions[0].File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789abcdef" {
+		t.Errorf("region 0: got %q", data)
+	}
+	if err := checkDumpMode("bogus"); err == nil {
+		t.Error("expected error for unknown dump mode")
+	}
+}
+
+func TestDumpLimit(t *testing.T) {
+	for _, c := range []struct {
+		perProcess, total, dumped, limit int64
+		ok                               bool
+	}{
+		{100, 0, 1000, 100, true},
+		{100, 1000, 0, 100, true},
+		{100, 1000, 950, 50, true},
+		{0, 1000, 950, 50, true},
+		{100, 1000, 1000, 0, false},
+		{100, 1000, 1200, 0, false},
+	} {
+		limit, ok := dumpLimit(c.perProcess, c.total, c.dumped)
+		if limit != c.limit || ok != c.ok {
+			t.Errorf("dumpLimit(%d, %d, %d) = %d, %v; expected %d, %v",
+				c.perProcess, c.total, c.dumped, limit, ok, c.limit, c.ok)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package yara
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+// regionFilter selects the memory regions of a process that are
+// scanned. A region must satisfy every condition that is set.
+type regionFilter struct {
+	exec, write, anon, file bool
+}
+
+func parseRegionFilter(values []string) (*regionFilter, error) {
+	f := &regionFilter{}
+	for _, v := range values {
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "all", "":
+		case "exec":
+			f.exec = true
+		case "write":
+			f.write = true
+		case "anon":
+			f.anon = true
+		case "file":
+			f.file = true
+		default:
+			return nil, errors.New("unknown memory region filter: " + v)
+		}
+	}
+	if f.anon && f.file {
+		return nil, errors.New("memory region filters anon and file are mutually exclusive")
+	}
+	return f, nil
+}
+
+func (f *regionFilter) match(m procfs.Mapping) bool {
+	if len(m.Perms) < 3 || m.Perms[0] != 'r' {
+		return false
+	}
+	// The vsyscall page cannot be read through /proc/<pid>/mem.
+	if m.Path == "[vsyscall]" || m.Path == "[vvar]" {
+		return false
+	}
+	switch {
+	case f.exec && m.Perms[2] != 'x':
+		return false
+	case f.write && m.Perms[1] != 'w':
+		return false
+	case f.anon && !m.Anonymous():
+		return false
+	case f.file && m.Anonymous():
+		return false
+	}
+	return true
+}
+
+// maxMatchAddresses limits the number of string match addresses that
+// are reported per region.
+const maxMatchAddresses = 10
+
+// regionInfo returns report fields describing the memory region in
+// which a rule matched.
+func regionInfo(m procfs.Mapping, match yr.MatchRule) []string {
+	var addrs []string
+	seen := make(map[uint64]bool)
+	for _, s := range match.Strings {
+		addr := m.Start + s.Offset
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		if len(addrs) == maxMatchAddresses {
+			addrs = append(addrs, "...")
+			break
+		}
+		addrs = append(addrs, fmt.Sprintf("0x%x", addr))
+	}
+	return []string{
+		"region_start", fmt.Sprintf("0x%x", m.Start),
+		"region_end", fmt.Sprintf("0x%x", m.End),
+		"region_perms", m.Perms,
+		"region_file", m.Path,
+		"match_addresses", strings.Join(addrs, "|"),
+	}
+}
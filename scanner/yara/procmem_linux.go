@@ -0,0 +1,103 @@
+// +build linux
+
+package yara
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/procfs"
+)
+
+// maxRegionSize is the size above which memory regions are not
+// scanned.
+const maxRegionSize = 256 * 1024 * 1024
+
+// scanRegions scans the memory regions of a process that are selected
+// by the region filter one by one. For each match, the region it was
+// found in is returned at the same index.
+func (s *procScanner) scanRegions(pid int) (yr.MatchRules, []procfs.Mapping, error) {
+	p := procfs.Default.Proc(pid)
+	maps, err := p.Maps()
+	if err != nil {
+		return nil, nil, err
+	}
+	mem, err := openMem(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer mem.Close()
+	var (
+		matches yr.MatchRules
+		regions []procfs.Mapping
+		buf     []byte
+	)
+	for _, m := range maps {
+		if !s.regions.match(m) {
+			continue
+		}
+		size := m.End - m.Start
+		if size > maxRegionSize || m.Start > math.MaxInt64 {
+			log.Debugf("Skipping memory region %x-%x of pid %d", m.Start, m.End, pid)
+			continue
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		n, err := mem.ReadAt(buf, int64(m.Start))
+		if n == 0 {
+			log.Debugf("Could not read memory region %x-%x of pid %d: %v", m.Start, m.End, pid, err)
+			continue
+		}
+		if identicalToDisk(m, buf[:n]) {
+			continue
+		}
+		var rm yr.MatchRules
+		if err := s.rules.ScanMem(buf[:n], 0, time.Minute, &rm); err != nil {
+			log.Debugf("Error scanning memory region %x-%x of pid %d: %v", m.Start, m.End, pid, err)
+		}
+		for _, r := range rm {
+			matches = append(matches, r)
+			regions = append(regions, m)
+		}
+	}
+	return matches, regions, nil
+}
+
+// openMem opens the memory of a process for reading.
+func openMem(p procfs.Proc) (*os.File, error) { return os.Open(p.Path("mem")) }
+
+// identicalToDisk reports whether the contents of a file-backed
+// region are the same as the file it is mapped from. The part of the
+// region beyond the end of the file is expected to be zero.
+func identicalToDisk(m procfs.Mapping, buf []byte) bool {
+	if m.Anonymous() || m.Deleted() || len(m.Path) == 0 || m.Path[0] != '/' {
+		return false
+	}
+	f, err := os.Open(m.Path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	disk := make([]byte, len(buf))
+	n, err := f.ReadAt(disk, int64(m.Offset))
+	if err != nil && err != io.EOF {
+		return false
+	}
+	if !bytes.Equal(buf[:n], disk[:n]) {
+		return false
+	}
+	for _, b := range buf[n:] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+// +build linux
+
+package yara
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+func TestIdenticalToDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spyre-procmem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "lib.so")
+	if err := ioutil.WriteFile(file, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := procfs.Mapping{Start: 0x1000, End: 0x2000, Perms: "r--p", Offset: 4, Inode: 1, Path: file}
+	for _, c := range []struct {
+		buf  string
+		want bool
+	}{
+		{"456789", true},
+		{"456789\x00\x00", true},
+		{"456788", false},
+		{"456789\x00x", false},
+	} {
+		if got := identicalToDisk(m, []byte(c.buf)); got != c.want {
+			t.Errorf("identicalToDisk(%q) = %v, want %v", c.buf, got, c.want)
+		}
+	}
+	m.Path = file + " (deleted)"
+	if identicalToDisk(m, []byte("456789")) {
+		t.Error("deleted mapping must not be identical to disk")
+	}
+}
@@ -0,0 +1,26 @@
+// +build !linux
+
+package yara
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+// scanRegions falls back to scanning the whole process, since memory
+// regions can only be enumerated on Linux.
+func (s *procScanner) scanRegions(pid int) (yr.MatchRules, []procfs.Mapping, error) {
+	var matches yr.MatchRules
+	err := s.rules.ScanProc(pid, yr.ScanFlagsProcessMemory, 4*time.Minute, &matches)
+	return matches, nil, err
+}
+
+// openMem fails, since process memory cannot be read through procfs.
+func openMem(p procfs.Proc) (*os.File, error) {
+	return nil, errors.New("process memory dumps are not supported on this platform")
+}
@@ -0,0 +1,68 @@
+package yara
+
+import (
+	"testing"
+
+	yr "github.com/lprat/go-yara/v4"
+
+	"github.com/spyre-project/spyre/procfs"
+)
+
+func TestRegionFilter(t *testing.T) {
+	var (
+		text  = procfs.Mapping{Start: 0x400000, End: 0x401000, Perms: "r-xp", Inode: 42, Path: "/usr/bin/true"}
+		data  = procfs.Mapping{Start: 0x601000, End: 0x602000, Perms: "rw-p", Inode: 42, Path: "/usr/bin/true"}
+		heap  = procfs.Mapping{Start: 0x700000, End: 0x721000, Perms: "rw-p", Path: "[heap]"}
+		rwx   = procfs.Mapping{Start: 0x800000, End: 0x801000, Perms: "rwxp"}
+		guard = procfs.Mapping{Start: 0x900000, End: 0x901000, Perms: "---p"}
+	)
+	for _, c := range []struct {
+		filter []string
+		want   []bool // text, data, heap, rwx, guard
+	}{
+		{[]string{"all"}, []bool{true, true, true, true, false}},
+		{[]string{"exec"}, []bool{true, false, false, true, false}},
+		{[]string{"anon"}, []bool{false, false, true, true, false}},
+		{[]string{"exec", "anon"}, []bool{false, false, false, true, false}},
+		{[]string{"write", "file"}, []bool{false, true, false, false, false}},
+	} {
+		f, err := parseRegionFilter(c.filter)
+		if err != nil {
+			t.Fatalf("%v: %v", c.filter, err)
+		}
+		for i, m := range []procfs.Mapping{text, data, heap, rwx, guard} {
+			if got := f.match(m); got != c.want[i] {
+				t.Errorf("%v: match(%x-%x %s %s) = %v, want %v",
+					c.filter, m.Start, m.End, m.Perms, m.Path, got, c.want[i])
+			}
+		}
+	}
+	for _, filter := range [][]string{{"bogus"}, {"anon", "file"}} {
+		if _, err := parseRegionFilter(filter); err == nil {
+			t.Errorf("%v: expected error", filter)
+		}
+	}
+}
+
+func TestRegionInfo(t *testing.T) {
+	m := procfs.Mapping{Start: 0x1000, End: 0x3000, Perms: "rwxp"}
+	info := regionInfo(m, yr.MatchRule{Strings: []yr.MatchString{
+		{Name: "$a", Offset: 0x10}, {Name: "$b", Offset: 0x10}, {Name: "$c", Offset: 0x200},
+	}})
+	want := []string{
+		"region_start", "0x1000",
+		"region_end", "0x3000",
+		"region_perms", "rwxp",
+		"region_file", "",
+		"match_addresses", "0x1010|0x1200",
+	}
+	if len(info) != len(want) {
+		t.Fatalf("got %v, want %v", info, want)
+	}
+	for i := range want {
+		if info[i] != want[i] {
+			t.Errorf("got %v, want %v", info, want)
+			break
+		}
+	}
+}
@@ -6,6 +6,8 @@ import (
 	yr "github.com/lprat/go-yara/v4"
 	"github.com/shirou/gopsutil/v3/process"
 	"github.com/spyre-project/spyre/config"
+	"github.com/spyre-project/spyre/log"
+	"github.com/spyre-project/spyre/procfs"
 	"github.com/spyre-project/spyre/report"
 	"github.com/spyre-project/spyre/scanner"
 
@@ -36,14 +38,37 @@ func hash_file_md5(filePath string) (string, error) {
 
 }
 
-type procScanner struct{ rules *yr.Rules }
+type procScanner struct {
+	rules   *yr.Rules
+	regions *regionFilter
+	// dumped is the amount of memory dumped so far, see
+	// --proc-dump-total-size.
+	dumped int64
+}
 
 func (s *procScanner) Name() string { return "YARA-proc" }
 
 func (s *procScanner) Init() error {
 	var err error
-	s.rules, err = compile(procscan, config.YaraProcRules)
-	return err
+	s.rules, _, err = compile(procscan, config.YaraProcRules)
+	if err != nil {
+		return err
+	}
+	if len(config.ProcRegions) > 0 {
+		if s.regions, err = parseRegionFilter(config.ProcRegions); err != nil {
+			return err
+		}
+	}
+	if err := checkDumpMode(config.ProcDump); err != nil {
+		return err
+	}
+	// Memory dumps are written in plain text and would
+	// defeat the purpose of an encrypted report.
+	if config.ProcDump != "" && report.Encrypted() {
+		log.Error("Not dumping process memory: dumps are not encrypted, but the report is")
+		config.ProcDump = ""
+	}
+	return nil
 }
 
 func (s *procScanner) ScanProc(pid int32) error {
@@ -57,7 +82,8 @@ func (s *procScanner) ScanProc(pid int32) error {
     exe = ""
   }
 	if !(stringInSlice(exe, config.ProcIgnoreList)) {
-		return fmt.Errorf("Skipping process (found on ignore list) %s[%d].",exe,pid)
+		log.Debugf("Skipping process (found on ignore list) %s[%d].", exe, pid)
+		return scanner.ErrSkipped
 	}
 	ppidx, err := handle.Ppid()
 	ppid := ""
@@ -103,6 +129,10 @@ func (s *procScanner) ScanProc(pid int32) error {
 	if err != nil {
     crt_time = 0
   }
+	start_time := ""
+	if crt_time > 0 {
+		start_time = report.FormatTime(time.Unix(0, crt_time*int64(time.Millisecond)))
+	}
 	childrens, err := handle.Children()
 	var child_cmdline []string
 	var child_pathexe []string
@@ -164,8 +194,27 @@ func (s *procScanner) ScanProc(pid int32) error {
 			return err
 		}
 	}
-	err = s.rules.ScanProc(int(pid), yr.ScanFlagsProcessMemory, 4*time.Minute, &matches)
-	for _, m := range matches {
+	var regions []procfs.Mapping
+	if s.regions != nil {
+		matches, regions, err = s.scanRegions(int(pid))
+	} else {
+		err = s.rules.ScanProc(int(pid), yr.ScanFlagsProcessMemory, 4*time.Minute, &matches)
+	}
+	var dump []string
+	if len(matches) > 0 && config.ProcDump != "" {
+		dir, err := s.dumpProcess(int(pid), pathexe, cmdline, matches, regions)
+		if err != nil {
+			log.Errorf("Could not dump memory of process %s[%d]: %v", exe, pid, err)
+		}
+		if dir != "" {
+			dump = []string{"memory_dump", dir}
+		}
+	}
+	for i, m := range matches {
+		var region []string
+		if regions != nil {
+			region = regionInfo(regions[i], m)
+		}
 		var matchx []string
 		for _, ms := range m.Strings {
 			if stringInSlice(ms.Name+"-->"+string(ms.Data), matchx) {
@@ -186,8 +235,9 @@ func (s *procScanner) ScanProc(pid int32) error {
 		if err != nil {
 		  md5sum = ""
 		}
-		report.AddProcInfo("yara_on_pid", message,
+		report.AddProcInfo("yara_on_pid", message, append([]string{
 			"rule", m.Rule,
+			"process_key", procfs.Default.Proc(int(pid)).Key(),
 			"string_match", string(matched),
 			"PID", strconv.FormatInt(int64(pid), 10),
 			"PPID", ppid,
@@ -197,6 +247,7 @@ func (s *procScanner) ScanProc(pid int32) error {
 			"Process", exe,
 			"username", username,
 			"real_date", strconv.FormatInt(int64(crt_time),10),
+			"time_process_start", start_time,
 			"Parent_pathexe", ppathexe,
 			"Parent_cmdline", pcmdline,
 			"Parent_Process", pexe,
@@ -205,15 +256,17 @@ func (s *procScanner) ScanProc(pid int32) error {
 			"Child_pathexe", strings.Join(child_pathexe, "|"),
 			"Child_username", strings.Join(child_username, "|"),
 			"Child_Process", strings.Join(child_exe, "|"),
-		)
+		}, append(region, dump...)...)...)
 	}
 	if err != nil {
 		message := fmt.Sprintf("Error yara proc scan [%v] on process: %s[%s](%s)",err,exe,pathexe,username)
+		scanerr := err.Error()
 		md5sum, err := hash_file_md5(pathexe)
 		if err != nil {
 		  md5sum = ""
 		}
 		report.AddProcInfo("yara_on_pid", message,
+			"error", scanerr,
 			"PID", strconv.FormatInt(int64(pid), 10),
 			"PPID", ppid,
 			"Filehash", md5sum,
@@ -222,6 +275,7 @@ func (s *procScanner) ScanProc(pid int32) error {
 			"Process", exe,
 			"username", username,
 			"real_date", strconv.FormatInt(int64(crt_time),10),
+			"time_process_start", start_time,
 			"Parent_pathexe", ppathexe,
 			"Parent_cmdline", pcmdline,
 			"Parent_Process", pexe,